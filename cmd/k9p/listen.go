@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// listenFlag collects the addresses of repeated --listen flags.
+type listenFlag []string
+
+func (f *listenFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *listenFlag) Set(address string) error {
+	*f = append(*f, address)
+	return nil
+}
+
+// listen returns the listeners for a Plan 9 style address:
+//
+//	tcp!host!port	listen on a TCP port, with * as the host for all addresses
+//	unix!path	listen on a unix socket only accessible by the current user
+//	fd!n		listen on an inherited file descriptor
+//	namespace	listen on the unix socket k9p in the plan9port name space
+//	systemd		listen on the sockets passed by systemd socket activation
+func listen(address string) ([]net.Listener, error) {
+	switch address {
+	case "namespace":
+		ns, err := namespace()
+		if err != nil {
+			return nil, err
+		}
+		return single(listenUnix(filepath.Join(ns, "k9p")))
+	case "systemd":
+		return listenSystemd()
+	}
+
+	parts := strings.SplitN(address, "!", 3)
+	switch {
+	case parts[0] == "tcp" && len(parts) == 3:
+		host := parts[1]
+		if host == "*" {
+			host = ""
+		}
+		return single(net.Listen("tcp", net.JoinHostPort(host, parts[2])))
+	case parts[0] == "unix" && len(parts) >= 2:
+		return single(listenUnix(strings.TrimPrefix(address, "unix!")))
+	case parts[0] == "fd" && len(parts) == 2:
+		fd, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid file descriptor in %q", address)
+		}
+		return single(listenFd(fd))
+	}
+
+	return nil, fmt.Errorf("unknown listen address %q", address)
+}
+
+func single(ln net.Listener, err error) ([]net.Listener, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return []net.Listener{ln}, nil
+}
+
+// listenUnix listens on a unix socket at path, replacing any stale socket
+// left by an instance that exited. The socket is created only accessible by
+// the current user.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another server", path)
+		}
+		if !errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, err
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create the socket without group or other permissions, rather than
+	// changing them after others could connect. Listeners are created
+	// before serving, so nothing else is creating files meanwhile.
+	restore := privateUmask()
+	ln, err := net.Listen("unix", path)
+	restore()
+	if err != nil {
+		return nil, err
+	}
+
+	return ln, nil
+}
+
+func listenFd(fd int) (net.Listener, error) {
+	f := os.NewFile(uintptr(fd), "fd"+strconv.Itoa(fd))
+	if f == nil {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	defer f.Close()
+
+	return net.FileListener(f)
+}
+
+// listenSystemd returns listeners for the sockets passed with the
+// LISTEN_FDS protocol of systemd socket activation.
+func listenSystemd() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, errors.New("no sockets passed by systemd")
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LISTEN_FDS: %v", err)
+	}
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	// Passed file descriptors start after stdin, stdout and stderr.
+	const listenFdsStart = 3
+
+	listeners := make([]net.Listener, 0, n)
+	for fd := listenFdsStart; fd < listenFdsStart+n; fd++ {
+		ln, err := listenFd(fd)
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, ln)
+	}
+
+	return listeners, nil
+}
+
+// namespace returns the plan9port name space directory, creating it if
+// needed. Like plan9port, this is $NAMESPACE or /tmp/ns.$USER.$DISPLAY.
+func namespace() (string, error) {
+	ns := os.Getenv("NAMESPACE")
+	if ns == "" {
+		display := os.Getenv("DISPLAY")
+		if display == "" {
+			display = ":0.0"
+		}
+		display = strings.TrimSuffix(display, ".0")
+
+		u, err := user.Current()
+		if err != nil {
+			return "", err
+		}
+
+		ns = fmt.Sprintf("/tmp/ns.%s.%s", u.Username, display)
+	}
+
+	if err := os.MkdirAll(ns, 0700); err != nil {
+		return "", err
+	}
+
+	return ns, nil
+}
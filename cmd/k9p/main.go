@@ -2,21 +2,36 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
+	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
 
 	p9p "github.com/docker/go-p9p"
 	"github.com/oklog/run"
 	"github.com/rs/zerolog"
 	"go.terinstock.com/k9p/pkg/k9p"
 	"go.terinstock.com/k9p/pkg/k9p/logger"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/klog"
 )
 
+// handshakeTimeout is how long clients have to complete the TLS handshake.
+const handshakeTimeout = 10 * time.Second
+
 func main() {
 	fs := flag.NewFlagSet("k9p", flag.ExitOnError)
 	klog.InitFlags(fs)
@@ -25,14 +40,25 @@ func main() {
 	fs.Set("alsologtostderr", "false")
 
 	var (
-		prettyLog  = fs.Bool("pretty-log", false, "output human-friendly logs")
-		master     = fs.String("master", "", "The address of the Kubernetes API server (overrides any value in kubeconfig).")
-		kubeconfig = fs.String("kubeconfig", "", "Path to kubeconfig file with authorization and master location information.")
-		bind9p     = fs.String("bind-9p", ":564", "The address the 9P server should bind and listen on")
+		prettyLog   = fs.Bool("pretty-log", false, "output human-friendly logs")
+		master      = fs.String("master", "", "The address of the Kubernetes API server (overrides any value in kubeconfig).")
+		kubeconfig  = fs.String("kubeconfig", "", "Path to kubeconfig file with authorization and master location information.")
+		allContexts = fs.Bool("all-contexts", false, "Serve every kubeconfig context within the clusters directory, instead of only the current context. The attach name may select a context directly.")
+		bind9p      = fs.String("bind-9p", ":564", "The address the 9P server should bind and listen on, if --listen is not set")
+		stdio       = fs.Bool("stdio", false, "Serve a single 9P session on stdin and stdout instead of listening.")
+		tlsCert     = fs.String("tls-cert-file", "", "File containing the x509 certificate for serving 9P over TLS.")
+		tlsKey      = fs.String("tls-private-key-file", "", "File containing the x509 private key matching --tls-cert-file.")
+		shutdown    = fs.Duration("shutdown-timeout", 30*time.Second, "How long to wait for in-flight writes to finish when shutting down.")
+		clientCA    = fs.String("client-ca-file", "", "If set, clients must present a certificate signed by one of the authorities in this file. The certificate's common name and organizations are impersonated as the user and groups of the session.")
+		readOnly    = fs.Bool("read-only", false, "Refuse all writes, creates and removes.")
+		policyFile  = fs.String("policy-file", "", "File containing rules allowing or denying writes, creates and removes by path and user.")
 	)
+	var listenAddrs listenFlag
+	fs.Var(&listenAddrs, "listen", "An address to listen on: tcp!host!port, unix!path, fd!n, namespace or systemd. May be repeated.")
 	fs.Parse(os.Args[1:])
 
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	var log zerolog.Logger
 	if *prettyLog {
@@ -41,62 +67,316 @@ func main() {
 		log = zerolog.New(os.Stderr)
 	}
 
-	client, err := createClient(*master, *kubeconfig)
-	if err != nil {
-		log.Fatal().Err(err).Send()
+	var (
+		config        *rest.Config
+		client        kubernetes.Interface
+		dynamicClient dynamic.Interface
+		rules         = clientcmd.NewDefaultClientConfigLoadingRules()
+		rawConfig     *clientcmdapi.Config
+		contexts      []string
+		err           error
+	)
+	if *allContexts {
+		rules.ExplicitPath = *kubeconfig
+		rawConfig, err = rules.Load()
+		if err != nil {
+			log.Fatal().Err(err).Send()
+		}
+
+		for name := range rawConfig.Contexts {
+			contexts = append(contexts, name)
+		}
+		sort.Strings(contexts)
+	} else {
+		config, err = clientcmd.BuildConfigFromFlags(*master, *kubeconfig)
+		if err != nil {
+			log.Fatal().Err(err).Send()
+		}
+
+		client, dynamicClient, err = createClient(config)
+		if err != nil {
+			log.Fatal().Err(err).Send()
+		}
+	}
+
+	var tlsConfig *tls.Config
+	if *tlsCert != "" || *tlsKey != "" {
+		tlsConfig, err = createTLSConfig(*tlsCert, *tlsKey, *clientCA)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error loading TLS configuration")
+		}
+	} else if *clientCA != "" {
+		log.Fatal().Msg("--client-ca-file requires --tls-cert-file and --tls-private-key-file")
+	}
+
+	policy := &k9p.Policy{}
+	if *policyFile != "" {
+		policy, err = k9p.LoadPolicy(*policyFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error loading policy")
+		}
+	}
+	if *readOnly {
+		policy.ReadOnly = true
 	}
 
 	klog.SetOutput(log.With().Str("component", "klog").Logger())
 
+	var (
+		conns      sync.WaitGroup
+		sessionsMu sync.Mutex
+		sessions   = make(map[*k9p.Session]struct{})
+	)
+
+	serve := func(conn net.Conn) {
+		defer conns.Done()
+		defer conn.Close()
+
+		ctx, cancel := context.WithCancel(context.WithValue(ctx, "conn", conn))
+		defer cancel()
+
+		log.Info().Str("remote", conn.RemoteAddr().String()).Msg("connected")
+
+		var (
+			identity    string
+			impersonate *rest.ImpersonationConfig
+		)
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			// Don't let a client that never completes the handshake hold
+			// the connection open.
+			tlsConn.SetDeadline(time.Now().Add(handshakeTimeout))
+			if err := tlsConn.Handshake(); err != nil {
+				log.Warn().Err(err).Msg("TLS handshake")
+				return
+			}
+			tlsConn.SetDeadline(time.Time{})
+
+			if certs := tlsConn.ConnectionState().PeerCertificates; len(certs) > 0 {
+				subject := certs[0].Subject
+				identity = subject.CommonName
+				impersonate = &rest.ImpersonationConfig{
+					UserName: subject.CommonName,
+					Groups:   subject.Organization,
+				}
+
+				log.Info().Str("remote", conn.RemoteAddr().String()).Str("user", identity).Strs("groups", subject.Organization).Msg("authenticated")
+			}
+		}
+
+		var ksession *k9p.Session
+		if *allContexts {
+			ksession = k9p.NewMultiCluster(ctx, contexts, func(name string) (kubernetes.Interface, kubernetes.Interface, dynamic.Interface, error) {
+				config, err := clientcmd.NewNonInteractiveClientConfig(*rawConfig, name, &clientcmd.ConfigOverrides{}, rules).ClientConfig()
+				if err != nil {
+					return nil, nil, nil, err
+				}
+
+				server, dynamicClient, err := createClient(config)
+				if err != nil || impersonate == nil {
+					return server, server, dynamicClient, err
+				}
+
+				impersonated := rest.CopyConfig(config)
+				impersonated.Impersonate = *impersonate
+				client, dynamicClient, err := createClient(impersonated)
+				return server, client, dynamicClient, err
+			})
+		} else {
+			server, client, dynamicClient := client, client, dynamicClient
+			if impersonate != nil {
+				impersonated := rest.CopyConfig(config)
+				impersonated.Impersonate = *impersonate
+
+				var err error
+				client, dynamicClient, err = createClient(impersonated)
+				if err != nil {
+					log.Warn().Err(err).Msg("error creating impersonating client")
+					return
+				}
+			}
+
+			ksession = k9p.New(ctx, server, client, dynamicClient)
+		}
+		if identity != "" {
+			ksession.SetIdentity(identity)
+		}
+		ksession.SetPolicy(policy)
+
+		sessionsMu.Lock()
+		sessions[ksession] = struct{}{}
+		sessionsMu.Unlock()
+		defer func() {
+			sessionsMu.Lock()
+			delete(sessions, ksession)
+			sessionsMu.Unlock()
+		}()
+
+		session := logger.New(
+			log.With().Str("component", "9p").Logger(),
+			ksession,
+		)
+		if err := p9p.ServeConn(ctx, conn, p9p.Dispatch(session)); err != nil {
+			log.Warn().Err(err).Msg("ServeConn")
+		}
+	}
+
+	if *stdio {
+		conns.Add(1)
+		serve(stdioConn{})
+		return
+	}
+
+	listeners, err := createListeners(listenAddrs, *bind9p)
+	if err != nil {
+		log.Fatal().Err(err).Msg("error listening")
+	}
+
 	var g run.Group
 	{
-		ln, err := net.Listen("tcp", *bind9p)
-		if err != nil {
-			log.Fatal().Err(err).Msg("error listening")
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		stop := make(chan struct{})
+
+		g.Add(func() error {
+			select {
+			case s := <-sig:
+				return fmt.Errorf("received signal %s", s)
+			case <-stop:
+				return nil
+			}
+		}, func(error) {
+			signal.Stop(sig)
+			close(stop)
+		})
+	}
+
+	for _, ln := range listeners {
+		ln := ln
+		if tlsConfig != nil {
+			ln = tls.NewListener(ln, tlsConfig)
 		}
 
+		log.Info().Str("addr", ln.Addr().String()).Msg("listening")
+
 		g.Add(func() error {
 			for {
 				c, err := ln.Accept()
 				if err != nil {
-					log.Warn().Err(err).Msg("error accepting")
-					continue
+					if ne, ok := err.(net.Error); ok && ne.Temporary() {
+						log.Warn().Err(err).Msg("error accepting")
+						time.Sleep(100 * time.Millisecond)
+						continue
+					}
+					return err
 				}
 
-				go func(conn net.Conn) {
-					defer conn.Close()
-
-					ctx, cancel := context.WithCancel(context.WithValue(ctx, "conn", conn))
-					defer cancel()
-
-					log.Info().Str("remote", conn.RemoteAddr().String()).Msg("connected")
-
-					var session p9p.Session
-					{
-						ksession := k9p.New(ctx, client)
-						ksession.WaitForCacheSync(ctx.Done())
-						session = logger.New(
-							log.With().Str("component", "9p").Logger(),
-							ksession,
-						)
-					}
-					if err := p9p.ServeConn(ctx, conn, p9p.Dispatch(session)); err != nil {
-						log.Warn().Err(err).Msg("ServeConn")
-					}
-				}(c)
+				conns.Add(1)
+				go serve(c)
 			}
 		}, func(error) {
 			ln.Close()
 		})
 	}
 
-	log.Info().Err(g.Run())
+	err = g.Run()
+	log.Info().Err(err).Msg("shutting down")
+
+	// Listeners are closed, let in-flight writes finish before canceling
+	// the sessions and closing their connections.
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), *shutdown)
+	defer drainCancel()
+
+	var drains sync.WaitGroup
+	sessionsMu.Lock()
+	for ksession := range sessions {
+		drains.Add(1)
+		go func(ksession *k9p.Session) {
+			defer drains.Done()
+			if err := ksession.Drain(drainCtx); err != nil {
+				log.Warn().Err(err).Msg("in-flight writes did not finish")
+			}
+		}(ksession)
+	}
+	sessionsMu.Unlock()
+	drains.Wait()
+
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		conns.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info().Msg("exited cleanly")
+	case <-drainCtx.Done():
+		log.Warn().Msg("timed out waiting for connections to close")
+	}
+}
+
+func createClient(config *rest.Config) (kubernetes.Interface, dynamic.Interface, error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client, dynamicClient, nil
 }
 
-func createClient(master string, kubeconfig string) (kubernetes.Interface, error) {
-	config, err := clientcmd.BuildConfigFromFlags(master, kubeconfig)
+// createListeners listens on each address, or on the TCP address bind9p if
+// there are none.
+func createListeners(addresses []string, bind9p string) ([]net.Listener, error) {
+	if len(addresses) == 0 {
+		return single(net.Listen("tcp", bind9p))
+	}
+
+	var listeners []net.Listener
+	for _, address := range addresses {
+		lns, err := listen(address)
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, lns...)
+	}
+
+	return listeners, nil
+}
+
+func createTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
 	if err != nil {
 		return nil, err
 	}
-	return kubernetes.NewForConfig(config)
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		pem, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net"
+	"os"
+	"time"
+)
+
+// stdioConn is a net.Conn reading from stdin and writing to stdout, so a
+// single session can be served over a pipe, such as one set up by ssh.
+type stdioConn struct{}
+
+func (stdioConn) Read(p []byte) (int, error) {
+	return os.Stdin.Read(p)
+}
+
+func (stdioConn) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+func (stdioConn) Close() error {
+	os.Stdin.Close()
+	return os.Stdout.Close()
+}
+
+func (stdioConn) LocalAddr() net.Addr {
+	return stdioAddr{}
+}
+
+func (stdioConn) RemoteAddr() net.Addr {
+	return stdioAddr{}
+}
+
+// Deadlines are not supported, as stdin and stdout may not be pollable.
+
+func (stdioConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (stdioConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (stdioConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+type stdioAddr struct{}
+
+func (stdioAddr) Network() string {
+	return "stdio"
+}
+
+func (stdioAddr) String() string {
+	return "stdio"
+}
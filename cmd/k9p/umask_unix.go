@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import "syscall"
+
+// privateUmask sets a umask that removes group and other permissions from
+// created files, returning a function restoring the previous umask.
+func privateUmask() (restore func()) {
+	mask := syscall.Umask(0177)
+	return func() { syscall.Umask(mask) }
+}
@@ -0,0 +1,6 @@
+package main
+
+// privateUmask does nothing, as Windows has no umask.
+func privateUmask() (restore func()) {
+	return func() {}
+}
@@ -5,6 +5,7 @@ go 1.13
 require (
 	github.com/docker/go-p9p v0.0.0-20191112112554-37d97cf40d03
 	github.com/oklog/run v1.0.0
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/rs/zerolog v1.17.2
 	k8s.io/api v0.0.0-20191114100352-16d7abae0d2a
 	k8s.io/apimachinery v0.0.0-20191028221656-72ed19daf4bb
@@ -0,0 +1,95 @@
+package k9p
+
+import (
+	"context"
+	"fmt"
+
+	"go.terinstock.com/k9p/pkg/resources"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ClientFunc creates the clients for the named kubeconfig context: server,
+// with the credentials k9p runs with, and client and dynamic for the user of
+// the session, which may impersonate them.
+type ClientFunc func(context string) (server kubernetes.Interface, client kubernetes.Interface, dynamic dynamic.Interface, err error)
+
+// cluster holds the clients and informer caches of a Kubernetes cluster. The
+// caches are filled with k9p's own credentials, so they sync however little
+// the user may list, while requests and access reviews are made as the user.
+type cluster struct {
+	client         kubernetes.Interface
+	dynamic        dynamic.Interface
+	sharedInformer informers.SharedInformerFactory
+	access         *resources.Access
+}
+
+func newCluster(ctx context.Context, server kubernetes.Interface, client kubernetes.Interface, dynamic dynamic.Interface) *cluster {
+	sharedInformer := informers.NewSharedInformerFactory(server, 0)
+
+	sharedInformer.Core().V1().Namespaces().Informer()
+	sharedInformer.Core().V1().Nodes().Informer()
+	sharedInformer.Core().V1().Pods().Informer()
+	sharedInformer.Core().V1().Events().Informer()
+	sharedInformer.Apps().V1().Deployments().Informer()
+	sharedInformer.Apps().V1().StatefulSets().Informer()
+	sharedInformer.Apps().V1().DaemonSets().Informer()
+	sharedInformer.Apps().V1().ReplicaSets().Informer()
+	sharedInformer.Batch().V1().Jobs().Informer()
+	sharedInformer.Batch().V1beta1().CronJobs().Informer()
+
+	sharedInformer.Start(ctx.Done())
+
+	return &cluster{
+		client:         client,
+		dynamic:        dynamic,
+		sharedInformer: sharedInformer,
+		access:         resources.NewAccess(client),
+	}
+}
+
+// waitForCacheSync blocks until the informer caches have synced, returning
+// an error if stopCh is closed first.
+func (c *cluster) waitForCacheSync(stopCh <-chan struct{}) error {
+	for informerType, synced := range c.sharedInformer.WaitForCacheSync(stopCh) {
+		if !synced {
+			return fmt.Errorf("cache for %v did not sync", informerType)
+		}
+	}
+
+	return nil
+}
+
+// children returns the entries of the root directory of the cluster.
+func (c *cluster) children(session resources.Session) map[string]resources.Ref {
+	return map[string]resources.Ref{
+		"namespaces": resources.NewNamespacesRef(c.client, session),
+		"cluster": resources.NewDirRef("cluster", session, map[string]resources.Ref{
+			"nodes": resources.NewNodes(session),
+		}),
+	}
+}
+
+// clusterSession is the session of Refs within a cluster of a multi-cluster
+// session, using the clients and caches of that cluster.
+type clusterSession struct {
+	*Session
+	cluster *cluster
+}
+
+func (s clusterSession) Client() kubernetes.Interface {
+	return s.cluster.client
+}
+
+func (s clusterSession) Dynamic() dynamic.Interface {
+	return s.cluster.dynamic
+}
+
+func (s clusterSession) Informer() informers.SharedInformerFactory {
+	return s.cluster.sharedInformer
+}
+
+func (s clusterSession) Access() *resources.Access {
+	return s.cluster.access
+}
@@ -0,0 +1,133 @@
+package k9p
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Operations that may be restricted by a Policy.
+const (
+	OpWrite  = "write"
+	OpCreate = "create"
+	OpRemove = "remove"
+)
+
+// Policy decides which users may write, create or remove files. Rules are
+// checked in order and the first rule matching the operation, path and user
+// decides. Operations matching no rule are allowed, unless the policy is
+// read-only.
+//
+// A policy file is YAML, such as:
+//
+//	rules:
+//	- action: deny
+//	  operations: [remove]
+//	  path: clusters/*/namespaces/kube-system
+//	- action: allow
+//	  users: [alice]
+//	  path: namespaces/*/deployments/*/scale
+//	- action: deny
+//	  operations: [write]
+//	  path: namespaces/*/deployments/*/scale
+type Policy struct {
+	// ReadOnly denies every operation, regardless of the rules.
+	ReadOnly bool   `json:"readOnly,omitempty"`
+	Rules    []Rule `json:"rules,omitempty"`
+}
+
+// Rule allows or denies operations on the files matching a path glob, and
+// the files beneath them. Paths are from the root of the whole tree, without
+// a leading slash, regardless of the attach name. Objects are matched by
+// their path within their namespace or the cluster directory, however they
+// were reached. Users are the identities authenticated by the transport, such
+// as the common name of a TLS client certificate, and rules naming users never
+// match unauthenticated sessions. Empty users or operations match any.
+type Rule struct {
+	Action     string   `json:"action"`
+	Path       string   `json:"path"`
+	Users      []string `json:"users,omitempty"`
+	Operations []string `json:"operations,omitempty"`
+}
+
+// LoadPolicy reads a policy file.
+func LoadPolicy(filename string) (*Policy, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy Policy
+	if err := yaml.UnmarshalStrict(data, &policy); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", filename, err)
+	}
+
+	for i, rule := range policy.Rules {
+		if rule.Action != "allow" && rule.Action != "deny" {
+			return nil, fmt.Errorf("rule %d: action must be allow or deny, not %q", i, rule.Action)
+		}
+		if _, err := path.Match(rule.Path, ""); err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i, err)
+		}
+		for _, op := range rule.Operations {
+			if op != OpWrite && op != OpCreate && op != OpRemove {
+				return nil, fmt.Errorf("rule %d: unknown operation %q", i, op)
+			}
+		}
+	}
+
+	return &policy, nil
+}
+
+// Allowed reports if user may perform op on the file at name. The user is
+// empty if the session's identity wasn't authenticated.
+func (p *Policy) Allowed(op string, name string, user string) bool {
+	if p == nil {
+		return true
+	}
+	if p.ReadOnly {
+		return false
+	}
+
+	name = strings.Trim(path.Clean("/"+name), "/")
+	for _, rule := range p.Rules {
+		if rule.matches(op, name, user) {
+			return rule.Action == "allow"
+		}
+	}
+
+	return true
+}
+
+func (r Rule) matches(op string, name string, user string) bool {
+	if len(r.Operations) > 0 && !contains(r.Operations, op) {
+		return false
+	}
+	if len(r.Users) > 0 && (user == "" || !contains(r.Users, user)) {
+		return false
+	}
+
+	// Match the path or any of its parents, so a rule applies to everything
+	// beneath the directories it names.
+	elements := strings.Split(name, "/")
+	for i := len(elements); i > 0; i-- {
+		if ok, _ := path.Match(r.Path, strings.Join(elements[:i], "/")); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,60 @@
+package k9p
+
+import "testing"
+
+func TestPolicyAllowed(t *testing.T) {
+	policy := &Policy{Rules: []Rule{
+		{Action: "deny", Operations: []string{OpRemove}, Path: "namespaces/kube-system"},
+		{Action: "allow", Users: []string{"alice"}, Path: "namespaces/*/deployments/*/scale"},
+		{Action: "deny", Operations: []string{OpWrite}, Path: "namespaces/*/deployments/*/scale"},
+		{Action: "deny", Path: "clusters/prod/*"},
+	}}
+
+	tests := []struct {
+		op   string
+		name string
+		user string
+		want bool
+	}{
+		{OpWrite, "namespaces/default/deployments/web/scale", "", false},
+		{OpWrite, "/namespaces/default/deployments/web/scale", "", false},
+		{OpWrite, "namespaces/default/deployments/web/../web/scale", "", false},
+		{OpWrite, "namespaces/default/deployments/web/scale", "bob", false},
+		{OpWrite, "namespaces/default/deployments/web/scale", "alice", true},
+		{OpCreate, "namespaces/default/deployments/web/scale", "", true},
+		{OpWrite, "namespaces/default/deployments/web/spec.yaml", "", true},
+		{OpWrite, "namespaces/default/statefulsets/db/scale", "", true},
+
+		// Rules apply beneath the paths they match.
+		{OpRemove, "namespaces/kube-system", "", false},
+		{OpRemove, "namespaces/kube-system/pods/dns-1", "alice", false},
+		{OpRemove, "namespaces/default/pods/web-1", "", true},
+		{OpWrite, "namespaces/kube-system/pods/dns-1/labels/app", "", true},
+		{OpWrite, "clusters/prod/namespaces/default/pods/web-1/labels/app", "alice", false},
+		{OpWrite, "clusters/staging/namespaces/default/pods/web-1/labels/app", "", true},
+	}
+
+	for _, test := range tests {
+		if got := policy.Allowed(test.op, test.name, test.user); got != test.want {
+			t.Errorf("Allowed(%q, %q, %q) = %v, want %v", test.op, test.name, test.user, got, test.want)
+		}
+	}
+}
+
+func TestPolicyReadOnly(t *testing.T) {
+	policy := &Policy{
+		ReadOnly: true,
+		Rules:    []Rule{{Action: "allow", Path: "*"}},
+	}
+
+	for _, op := range []string{OpWrite, OpCreate, OpRemove} {
+		if policy.Allowed(op, "namespaces/default/pods/web-1/labels/app", "alice") {
+			t.Errorf("read-only policy allowed %s", op)
+		}
+	}
+
+	var none *Policy
+	if !none.Allowed(OpRemove, "namespaces/default/pods/web-1", "") {
+		t.Error("no policy denied remove")
+	}
+}
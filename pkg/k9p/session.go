@@ -2,39 +2,270 @@ package k9p
 
 import (
 	"context"
-	"runtime"
+	"net/url"
+	"path"
+	"strings"
 	"sync"
 
 	"github.com/docker/go-p9p"
+	"github.com/rs/zerolog/log"
 	"go.terinstock.com/k9p/pkg/resources"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 )
 
 type Session struct {
 	sync.Mutex
-	aname string
-	uname string
+	aname    string
+	uname    string
+	identity string
+
+	inflight sync.WaitGroup
+	draining bool
+	policy   *Policy
+
+	ctx     context.Context
+	cluster *cluster
+	refs    map[p9p.Fid]resources.Ref
+	paths   map[p9p.Fid]string
+
+	// Multi-cluster sessions create the clusters of each kubeconfig context
+	// on first use.
+	contexts   []string
+	newClient  ClientFunc
+	clustersMu sync.Mutex
+	clusters   map[string]*cluster
+}
 
-	client         kubernetes.Interface
-	sharedInformer informers.SharedInformerFactory
-	refs           map[p9p.Fid]resources.Ref
+// New returns a session of a single cluster. The informer caches are filled
+// using server, while client and dynamic make requests for the user, and may
+// impersonate them. Without impersonation, all three use the same
+// credentials.
+func New(ctx context.Context, server kubernetes.Interface, client kubernetes.Interface, dynamic dynamic.Interface) *Session {
+	return &Session{
+		ctx:     ctx,
+		cluster: newCluster(ctx, server, client, dynamic),
+		refs:    make(map[p9p.Fid]resources.Ref),
+		paths:   make(map[p9p.Fid]string),
+	}
 }
 
-func New(ctx context.Context, client kubernetes.Interface) *Session {
-	sharedInformer := informers.NewSharedInformerFactory(client, 0)
+// NewMultiCluster returns a session with a directory for each of contexts
+// within the clusters directory. The clients of a context are created with
+// newClient when its directory is first used.
+func NewMultiCluster(ctx context.Context, contexts []string, newClient ClientFunc) *Session {
+	return &Session{
+		ctx:       ctx,
+		contexts:  contexts,
+		newClient: newClient,
+		clusters:  make(map[string]*cluster),
+		refs:      make(map[p9p.Fid]resources.Ref),
+		paths:     make(map[p9p.Fid]string),
+	}
+}
 
-	sharedInformer.Core().V1().Namespaces().Informer()
-	sharedInformer.Apps().V1().Deployments().Informer()
+// getCluster returns the cluster of the named kubeconfig context, creating
+// it if needed and waiting until ctx is done for its caches to sync.
+func (k *Session) getCluster(ctx context.Context, name string) (*cluster, error) {
+	c, err := k.loadCluster(name)
+	if err != nil {
+		return nil, err
+	}
 
-	go sharedInformer.Start(ctx.Done())
-	runtime.Gosched()
+	// The caches of a new cluster sync in the background, so requests for
+	// other clusters aren't held up while waiting.
+	if err := c.waitForCacheSync(ctx.Done()); err != nil {
+		return nil, err
+	}
 
-	return &Session{
-		client:         client,
-		sharedInformer: sharedInformer,
-		refs:           make(map[p9p.Fid]resources.Ref),
+	return c, nil
+}
+
+// loadCluster returns the cluster of the named kubeconfig context, creating
+// it if needed.
+func (k *Session) loadCluster(name string) (*cluster, error) {
+	k.clustersMu.Lock()
+	defer k.clustersMu.Unlock()
+
+	if c, ok := k.clusters[name]; ok {
+		return c, nil
+	}
+
+	server, client, dynamic, err := k.newClient(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCluster(k.ctx, server, client, dynamic)
+	k.clusters[name] = c
+	return c, nil
+}
+
+// root returns the root directory of the session, and its path within the
+// whole tree. The aname is a path within the tree, such as
+// /namespaces/payments, which becomes the root so clients can only reach
+// that subtree. For multi-cluster sessions, an aname starting with the name
+// of a context attaches within that cluster.
+func (k *Session) root(ctx context.Context, aname string) (resources.Ref, string, error) {
+	var root resources.Ref
+	rootPath := "/"
+	if k.cluster != nil {
+		root = resources.NewDirRef("/", k, k.cluster.children(k))
+	} else if name, rest, ok := k.splitContext(aname); ok {
+		c, err := k.getCluster(ctx, name)
+		if err != nil {
+			return nil, "", err
+		}
+
+		root = resources.NewDirRef("/", clusterSession{k, c}, c.children(clusterSession{k, c}))
+		rootPath = path.Join("/clusters", url.PathEscape(name))
+		aname = rest
+	} else {
+		clusters := make(map[string]resources.Ref, len(k.contexts))
+		for _, name := range k.contexts {
+			name := name
+			escaped := url.PathEscape(name)
+			clusters[escaped] = resources.NewLazyDirRef(escaped, k, func(ctx context.Context) (map[string]resources.Ref, error) {
+				c, err := k.getCluster(ctx, name)
+				if err != nil {
+					return nil, err
+				}
+
+				return c.children(clusterSession{k, c}), nil
+			})
+		}
+
+		root = resources.NewDirRef("/", k, map[string]resources.Ref{
+			"clusters": resources.NewDirRef("clusters", k, clusters),
+		})
+	}
+
+	for _, element := range strings.Split(aname, "/") {
+		if element == "" {
+			continue
+		}
+
+		ref, err := walk(ctx, root, element)
+		if err != nil {
+			return nil, "", p9p.ErrBadattach
+		}
+		root = ref
+		rootPath = k.childPath(rootPath, element, ref)
+	}
+
+	return root, rootPath, nil
+}
+
+// splitContext splits an aname into the longest context name it starts
+// with, and the path following it. Context names may contain slashes, as
+// with EKS cluster ARNs, and may be given as is or escaped as they are named
+// within the clusters directory.
+func (k *Session) splitContext(aname string) (name string, rest string, ok bool) {
+	matched := 0
+	for _, c := range k.contexts {
+		for _, prefix := range []string{c, url.PathEscape(c)} {
+			if len(prefix) <= matched {
+				continue
+			}
+			if aname == prefix || strings.HasPrefix(aname, prefix+"/") {
+				name, rest, ok = c, aname[len(prefix):], true
+				matched = len(prefix)
+			}
+		}
+	}
+
+	return name, rest, ok
+}
+
+// qid returns the qid of ref, avoiding finding the rest of its Info if
+// possible.
+func qid(ref resources.Ref) p9p.Qid {
+	if qider, ok := ref.(resources.Qider); ok {
+		return qider.Qid()
+	}
+
+	return ref.Info().Qid
+}
+
+// previews reports if writing ref only previews changes, so isn't restricted
+// by the policy.
+func previews(ref resources.Ref) bool {
+	previewer, ok := ref.(resources.Previewer)
+	return ok && previewer.Previews()
+}
+
+// walk returns the named child of ref, bounding any loading of its children
+// by ctx.
+func walk(ctx context.Context, ref resources.Ref, name string) (resources.Ref, error) {
+	if walker, ok := ref.(resources.Walker); ok {
+		return walker.Walk(ctx, name)
+	}
+
+	return ref.Get(name)
+}
+
+// SetIdentity sets the user of the session when the transport has already
+// authenticated the client, such as with a TLS client certificate. The user
+// named in attach requests is then ignored.
+func (k *Session) SetIdentity(uname string) {
+	k.identity = uname
+}
+
+// SetPolicy sets the policy deciding which writes, creates and removes are
+// allowed. Without a policy, all are passed on to the refs.
+func (k *Session) SetPolicy(policy *Policy) {
+	k.policy = policy
+}
+
+// allowed checks the policy allows the session's user to perform op on the
+// file at name, returning a permission denied error if not. Only identities
+// authenticated by the transport are matched against the users of rules, as
+// anyone can claim any user when attaching.
+func (k *Session) allowed(op string, name string) error {
+	if !k.policy.Allowed(op, name, k.identity) {
+		return p9p.ErrPerm
+	}
+
+	return nil
+}
+
+// childPath returns the path of child, walked to by name from the file at
+// parent. Objects and collections reached through label selections, owners
+// directories and other aliases have the canonical path of the object or
+// collection, so policies apply however they are reached.
+func (k *Session) childPath(parent string, name string, child resources.Ref) string {
+	if pather, ok := child.(resources.Pather); ok {
+		if canonical := pather.Path(); canonical != "" {
+			return path.Join(k.clusterRoot(parent), canonical)
+		}
+	}
+
+	return path.Join(parent, name)
+}
+
+// clusterRoot returns the path of the root directory of the cluster
+// containing the file at name.
+func (k *Session) clusterRoot(name string) string {
+	if k.cluster != nil {
+		return "/"
+	}
+
+	elements := strings.SplitN(strings.TrimPrefix(name, "/"), "/", 3)
+	if len(elements) < 2 || elements[0] != "clusters" {
+		return "/"
 	}
+
+	return path.Join("/", elements[0], elements[1])
+}
+
+// getPath returns the path of the file fid refers to, from the root of the
+// whole tree.
+func (k *Session) getPath(fid p9p.Fid) string {
+	k.Lock()
+	defer k.Unlock()
+
+	return k.paths[fid]
 }
 
 func (k *Session) getRef(fid p9p.Fid) (resources.Ref, error) {
@@ -53,7 +284,7 @@ func (k *Session) getRef(fid p9p.Fid) (resources.Ref, error) {
 	return ref, nil
 }
 
-func (k *Session) newRef(fid p9p.Fid, resource resources.Ref) (resources.Ref, error) {
+func (k *Session) newRef(fid p9p.Fid, name string, resource resources.Ref) (resources.Ref, error) {
 	k.Lock()
 	defer k.Unlock()
 
@@ -68,6 +299,7 @@ func (k *Session) newRef(fid p9p.Fid, resource resources.Ref) (resources.Ref, er
 
 	ref := resource
 	k.refs[fid] = ref
+	k.paths[fid] = name
 	return ref, nil
 }
 
@@ -76,6 +308,10 @@ func (k *Session) Auth(ctx context.Context, afid p9p.Fid, uname string, aname st
 }
 
 func (k *Session) Attach(ctx context.Context, fid p9p.Fid, afid p9p.Fid, uname string, aname string) (p9p.Qid, error) {
+	if k.identity != "" {
+		uname = k.identity
+	}
+
 	if uname == "" {
 		return p9p.Qid{}, p9p.MessageRerror{Ename: "no user"}
 	}
@@ -84,35 +320,84 @@ func (k *Session) Attach(ctx context.Context, fid p9p.Fid, afid p9p.Fid, uname s
 		aname = "/"
 	}
 
+	// Wait with the request context, so a flushed attach or closed
+	// connection doesn't leave us waiting on caches that may never sync.
+	if err := k.WaitForCacheSync(ctx.Done()); err != nil {
+		return p9p.Qid{}, err
+	}
+
 	k.uname = uname
 	k.aname = aname
 
-	ref, err := k.newRef(fid, resources.NewDirRef("/", k, map[string]resources.Ref{
-		"namespaces": resources.NewNamespacesRef(k.client, k),
-		"cluster":    resources.NewDirRef("cluster", k, map[string]resources.Ref{}),
-	}))
+	root, name, err := k.root(ctx, aname)
+	if err != nil {
+		return p9p.Qid{}, err
+	}
+
+	ref, err := k.newRef(fid, name, root)
 	if err != nil {
 		return p9p.Qid{}, err
 	}
 
-	return ref.Info().Qid, nil
+	return qid(ref), nil
 }
 
 func (k *Session) Clunk(ctx context.Context, fid p9p.Fid) error {
-	_, err := k.getRef(fid)
+	ref, err := k.getRef(fid)
 	if err != nil {
 		return err
 	}
 
+	// Clunks may commit buffered writes, so while draining the fid is
+	// kept rather than silently dropping them.
+	if err := k.begin(); err != nil {
+		if _, ok := ref.(resources.Closer); ok {
+			log.Warn().Str("path", k.getPath(fid)).Msg("not closing file while draining, buffered writes are discarded")
+		}
+		return err
+	}
+	defer k.inflight.Done()
+
 	k.Lock()
-	defer k.Unlock()
 	delete(k.refs, fid)
+	delete(k.paths, fid)
+	k.Unlock()
+
+	if closer, ok := ref.(resources.Closer); ok {
+		return closer.Close(ctx)
+	}
 
 	return nil
 }
 
 func (k *Session) Remove(ctx context.Context, fid p9p.Fid) error {
-	return p9p.ErrUnknownMsg
+	ref, err := k.getRef(fid)
+	if err != nil {
+		return err
+	}
+	name := k.getPath(fid)
+
+	// Remove clunks the fid, even if the remove fails.
+	k.Lock()
+	delete(k.refs, fid)
+	delete(k.paths, fid)
+	k.Unlock()
+
+	if err := k.allowed(OpRemove, name); err != nil {
+		return err
+	}
+
+	if err := k.begin(); err != nil {
+		return err
+	}
+	defer k.inflight.Done()
+
+	remover, ok := ref.(resources.Remover)
+	if !ok {
+		return p9p.ErrNoremove
+	}
+
+	return remover.Remove(ctx)
 }
 
 func (k *Session) Walk(ctx context.Context, fid p9p.Fid, newfid p9p.Fid, names ...string) ([]p9p.Qid, error) {
@@ -123,22 +408,23 @@ func (k *Session) Walk(ctx context.Context, fid p9p.Fid, newfid p9p.Fid, names .
 		return qids, err
 	}
 
-	current := ref
+	current, currentPath := ref, k.getPath(fid)
 	for _, name := range names {
-		newResource, err := current.Get(name)
+		newResource, err := walk(ctx, current, name)
 		if err != nil {
 			break
 		}
 
-		qids = append(qids, newResource.Info().Qid)
+		qids = append(qids, qid(newResource))
 		current = newResource
+		currentPath = k.childPath(currentPath, name, newResource)
 	}
 
 	if len(qids) != len(names) {
 		return qids, nil
 	}
 
-	_, err = k.newRef(newfid, current)
+	_, err = k.newRef(newfid, currentPath, current)
 	if err != nil {
 		return qids, err
 	}
@@ -156,7 +442,28 @@ func (k *Session) Read(ctx context.Context, fid p9p.Fid, p []byte, offset int64)
 }
 
 func (k *Session) Write(ctx context.Context, fid p9p.Fid, p []byte, offset int64) (n int, err error) {
-	return 0, p9p.ErrUnknownMsg
+	if err := k.begin(); err != nil {
+		return 0, err
+	}
+	defer k.inflight.Done()
+
+	ref, err := k.getRef(fid)
+	if err != nil {
+		return 0, err
+	}
+
+	if !previews(ref) {
+		if err := k.allowed(OpWrite, k.getPath(fid)); err != nil {
+			return 0, err
+		}
+	}
+
+	writer, ok := ref.(resources.Writer)
+	if !ok {
+		return 0, p9p.ErrNowrite
+	}
+
+	return writer.Write(ctx, p, offset)
 }
 
 func (k *Session) Open(ctx context.Context, fid p9p.Fid, mode p9p.Flag) (p9p.Qid, uint32, error) {
@@ -165,11 +472,62 @@ func (k *Session) Open(ctx context.Context, fid p9p.Fid, mode p9p.Flag) (p9p.Qid
 		return p9p.Qid{}, 0, err
 	}
 
-	return ref.Info().Qid, 0, nil
+	// Refuse opening for writing up front, so editors fail before the user
+	// has made their changes.
+	writing := mode&3 == p9p.OWRITE || mode&3 == p9p.ORDWR || mode&p9p.OTRUNC != 0
+	if writing && !previews(ref) {
+		if err := k.allowed(OpWrite, k.getPath(fid)); err != nil {
+			return p9p.Qid{}, 0, err
+		}
+	}
+
+	if opener, ok := ref.(resources.Opener); ok {
+		opened, err := opener.Open(ctx, mode)
+		if err != nil {
+			return p9p.Qid{}, 0, err
+		}
+
+		k.Lock()
+		k.refs[fid] = opened
+		k.Unlock()
+		ref = opened
+	}
+
+	return qid(ref), 0, nil
 }
 
 func (k *Session) Create(ctx context.Context, parent p9p.Fid, name string, perm uint32, mode p9p.Flag) (p9p.Qid, uint32, error) {
-	return p9p.Qid{}, 0, p9p.ErrUnknownMsg
+	if err := k.begin(); err != nil {
+		return p9p.Qid{}, 0, err
+	}
+	defer k.inflight.Done()
+
+	ref, err := k.getRef(parent)
+	if err != nil {
+		return p9p.Qid{}, 0, err
+	}
+
+	createdPath := path.Join(k.getPath(parent), name)
+	if err := k.allowed(OpCreate, createdPath); err != nil {
+		return p9p.Qid{}, 0, err
+	}
+
+	creator, ok := ref.(resources.Creator)
+	if !ok {
+		return p9p.Qid{}, 0, p9p.ErrNocreate
+	}
+
+	created, err := creator.Create(ctx, name, perm, mode)
+	if err != nil {
+		return p9p.Qid{}, 0, err
+	}
+
+	k.Lock()
+	k.refs[parent] = created
+	k.paths[parent] = createdPath
+	k.Unlock()
+
+	return qid(created), 0, nil
 }
 
 func (k *Session) Stat(ctx context.Context, fid p9p.Fid) (p9p.Dir, error) {
@@ -192,14 +550,77 @@ func (k *Session) Version() (msize int, version string) {
 	return p9p.DefaultMSize, p9p.DefaultVersion
 }
 
-func (k *Session) WaitForCacheSync(stopCh <-chan struct{}) {
-	k.sharedInformer.WaitForCacheSync(stopCh)
+// errDraining is returned for writes, creates, removes and clunks started
+// after the session began draining.
+var errDraining = p9p.MessageRerror{Ename: "server shutting down"}
+
+// begin starts a write, create, remove or clunk, counting it as in-flight
+// until inflight.Done is called. It fails once the session is draining.
+func (k *Session) begin() error {
+	k.Lock()
+	defer k.Unlock()
+
+	if k.draining {
+		return errDraining
+	}
+
+	k.inflight.Add(1)
+	return nil
+}
+
+// Drain refuses new writes, creates, removes and clunks, then blocks until
+// those in-flight have finished, or ctx is done. Clunks may commit buffered
+// writes.
+func (k *Session) Drain(ctx context.Context) error {
+	k.Lock()
+	k.draining = true
+	k.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		k.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitForCacheSync blocks until the informer caches have synced, returning
+// an error if stopCh is closed first. The caches of multi-cluster sessions
+// are waited on when each cluster is first used.
+func (k *Session) WaitForCacheSync(stopCh <-chan struct{}) error {
+	if k.cluster == nil {
+		return nil
+	}
+
+	return k.cluster.waitForCacheSync(stopCh)
 }
 
 func (k *Session) GetAuth() (uname, aname string) {
 	return k.uname, k.aname
 }
 
+// Client, Dynamic, Informer and Access return the clients and caches of a
+// single-cluster session. Refs within multi-cluster sessions are given a
+// session for their own cluster.
+
+func (k *Session) Client() kubernetes.Interface {
+	return k.cluster.client
+}
+
+func (k *Session) Dynamic() dynamic.Interface {
+	return k.cluster.dynamic
+}
+
 func (k *Session) Informer() informers.SharedInformerFactory {
-	return k.sharedInformer
+	return k.cluster.sharedInformer
+}
+
+func (k *Session) Access() *resources.Access {
+	return k.cluster.access
 }
@@ -0,0 +1,393 @@
+package k9p
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/go-p9p"
+	"go.terinstock.com/k9p/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	kfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestSession(ctx context.Context) *Session {
+	client := kfake.NewSimpleClientset()
+	return New(ctx, client, client, fake.NewSimpleDynamicClient(runtime.NewScheme()))
+}
+
+func TestDrain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session := newTestSession(ctx)
+
+	// A write in flight when draining starts.
+	if err := session.begin(); err != nil {
+		t.Fatal(err)
+	}
+
+	drained := make(chan error, 1)
+	go func() {
+		drained <- session.Drain(context.Background())
+	}()
+
+	select {
+	case <-drained:
+		t.Fatal("drain finished with a write in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// New writes are refused while draining.
+	if _, err := session.Write(ctx, 1, []byte("x"), 0); err != errDraining {
+		t.Fatalf("got error %v writing while draining, want %v", err, errDraining)
+	}
+
+	session.inflight.Done()
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("drain did not finish after the write")
+	}
+}
+
+// closeRecorder is a file recording whether it was closed.
+type closeRecorder struct {
+	resources.Ref
+	closed bool
+}
+
+func (r *closeRecorder) Close(ctx context.Context) error {
+	r.closed = true
+	return nil
+}
+
+func TestClunkWhileDraining(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session := newTestSession(ctx)
+
+	ref := &closeRecorder{}
+	session.refs[1] = ref
+	session.paths[1] = "/spec.yaml"
+
+	if err := session.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The clunk is refused, rather than dropping the fid without
+	// committing its writes.
+	if err := session.Clunk(ctx, 1); err != errDraining {
+		t.Fatalf("got error %v clunking while draining, want %v", err, errDraining)
+	}
+	if ref.closed {
+		t.Error("file was closed while draining")
+	}
+	if _, err := session.getRef(1); err != nil {
+		t.Errorf("fid was removed by a refused clunk: %v", err)
+	}
+}
+
+func TestGetClusterDoesNotBlockOthers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The caches of the slow cluster never sync, as listing blocks.
+	blocked := make(chan struct{})
+	defer close(blocked)
+
+	session := NewMultiCluster(ctx, []string{"slow", "fast"}, func(name string) (kubernetes.Interface, kubernetes.Interface, dynamic.Interface, error) {
+		client := kfake.NewSimpleClientset()
+		if name == "slow" {
+			client.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				<-blocked
+				return false, nil, nil
+			})
+		}
+		return client, client, fake.NewSimpleDynamicClient(runtime.NewScheme()), nil
+	})
+
+	slowCtx, slowCancel := context.WithCancel(ctx)
+	slow := make(chan error, 1)
+	go func() {
+		_, err := session.getCluster(slowCtx, "slow")
+		slow <- err
+	}()
+
+	fastCtx, fastCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer fastCancel()
+	if _, err := session.getCluster(fastCtx, "fast"); err != nil {
+		t.Fatalf("getting a cluster while another syncs: %v", err)
+	}
+
+	slowCancel()
+	select {
+	case err := <-slow:
+		if err == nil {
+			t.Fatal("got no error for a cluster that did not sync")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting for the caches did not stop with the request")
+	}
+}
+
+func TestAttachEscapedContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const name = "arn:aws:eks:us-east-1:123456789012:cluster/prod"
+	session := NewMultiCluster(ctx, []string{name, "staging"}, func(string) (kubernetes.Interface, kubernetes.Interface, dynamic.Interface, error) {
+		client := kfake.NewSimpleClientset()
+		return client, client, fake.NewSimpleDynamicClient(runtime.NewScheme()), nil
+	})
+
+	for _, test := range []struct {
+		aname string
+		path  string
+	}{
+		{aname: "/", path: "/"},
+		{aname: "/clusters/arn:aws:eks:us-east-1:123456789012:cluster%2Fprod/namespaces", path: "/clusters/arn:aws:eks:us-east-1:123456789012:cluster%2Fprod/namespaces"},
+		{aname: name + "/namespaces", path: "/clusters/arn:aws:eks:us-east-1:123456789012:cluster%2Fprod/namespaces"},
+		{aname: "arn:aws:eks:us-east-1:123456789012:cluster%2Fprod", path: "/clusters/arn:aws:eks:us-east-1:123456789012:cluster%2Fprod"},
+		{aname: "staging/cluster", path: "/clusters/staging/cluster"},
+	} {
+		_, path, err := session.root(ctx, test.aname)
+		if err != nil {
+			t.Errorf("root(%q): %v", test.aname, err)
+			continue
+		}
+		if path != test.path {
+			t.Errorf("root(%q) path = %q, want %q", test.aname, path, test.path)
+		}
+	}
+
+	if _, _, err := session.root(ctx, "/clusters/arn:aws:eks:us-east-1:123456789012:cluster/prod"); err == nil {
+		t.Error("got no error attaching to an unescaped context within clusters")
+	}
+}
+
+// newTestClient returns a fake clientset containing a deployment with a
+// replica set and pod, allowing the user to do anything.
+func newTestClient() *kfake.Clientset {
+	replicas := int32(1)
+	client := kfake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "web-uid", Labels: map[string]string{"app": "web"}},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "web-123",
+				Namespace:       "default",
+				UID:             "web-123-uid",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", UID: "web-uid"}},
+			},
+			Spec: appsv1.ReplicaSetSpec{Replicas: &replicas},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "web-123-a",
+				Namespace:       "default",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-123", UID: "web-123-uid"}},
+			},
+			Spec: corev1.PodSpec{NodeName: "node-1"},
+		},
+	)
+	client.PrependReactor("create", "selfsubjectrulesreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &authorizationv1.SelfSubjectRulesReview{Status: authorizationv1.SubjectRulesReviewStatus{
+			ResourceRules: []authorizationv1.ResourceRule{{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}},
+		}}, nil
+	})
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &authorizationv1.SelfSubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true}}, nil
+	})
+
+	return client
+}
+
+func TestPolicyAliases(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := newTestClient()
+
+	policy := &Policy{Rules: []Rule{
+		{Action: "allow", Users: []string{"alice"}, Path: "namespaces/*/deployments/*/scale"},
+		{Action: "deny", Operations: []string{OpWrite}, Path: "namespaces/*/deployments/*/scale"},
+		{Action: "deny", Operations: []string{OpWrite}, Path: "cluster/nodes"},
+	}}
+
+	newSession := func(identity string) *Session {
+		session := New(ctx, client, client, fake.NewSimpleDynamicClient(runtime.NewScheme()))
+		if identity != "" {
+			session.SetIdentity(identity)
+		}
+		session.SetPolicy(policy)
+
+		if _, err := session.Attach(ctx, 0, p9p.NOFID, "alice", "/"); err != nil {
+			t.Fatal(err)
+		}
+		return session
+	}
+
+	tests := []struct {
+		walk []string
+		path string
+	}{
+		{walk: []string{"namespaces", "default", "deployments", "web", "scale"}},
+		{walk: []string{"namespaces", "default", "deployments", "@app=web", "web", "scale"}},
+		{walk: []string{"namespaces", "default", "deployments", "%metadata.name=web", "web", "scale"}},
+		{walk: []string{"namespaces", "default", "replicasets", "web-123", "owners", "deployments", "web", "scale"}},
+		{walk: []string{"namespaces", "default", "pods", "web-123-a", "owners", "replicasets", "web-123", "owners", "deployments", "web", "scale"}},
+		{walk: []string{"namespaces", "default", "deployments", "web", "owned", "replicasets", "web-123", "owners", "deployments", "web", "scale"}},
+	}
+
+	// Without an authenticated identity, the attach name doesn't match the
+	// rule allowing alice.
+	session := newSession("")
+	for i, test := range tests {
+		fid := p9p.Fid(i + 1)
+		if _, err := session.Walk(ctx, 0, fid, test.walk...); err != nil {
+			t.Fatalf("walk %v: %v", test.walk, err)
+		}
+
+		if got, want := session.getPath(fid), "/namespaces/default/deployments/web/scale"; got != want {
+			t.Errorf("walk %v: got path %q, want %q", test.walk, got, want)
+		}
+		if _, _, err := session.Open(ctx, fid, p9p.OWRITE); err != p9p.ErrPerm {
+			t.Errorf("walk %v: got error %v opening for writing, want %v", test.walk, err, p9p.ErrPerm)
+		}
+	}
+
+	// Nodes are reached through pods, too.
+	if _, err := session.Walk(ctx, 0, 100, "namespaces", "default", "pods", "web-123-a", "node", "labels"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := session.getPath(100), "/cluster/nodes/node-1/labels"; got != want {
+		t.Errorf("got path %q for the node of a pod, want %q", got, want)
+	}
+
+	// With an authenticated identity, the rule allowing alice applies.
+	session = newSession("alice")
+	for i, test := range tests {
+		fid := p9p.Fid(i + 1)
+		if _, err := session.Walk(ctx, 0, fid, test.walk...); err != nil {
+			t.Fatalf("walk %v: %v", test.walk, err)
+		}
+		if _, _, err := session.Open(ctx, fid, p9p.OWRITE); err != nil {
+			t.Errorf("walk %v: got error %v opening for writing as alice", test.walk, err)
+		}
+	}
+}
+
+func TestReadOnlyDryRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newTestClient()
+	session := New(ctx, client, client, fake.NewSimpleDynamicClient(runtime.NewScheme()))
+	session.SetPolicy(&Policy{ReadOnly: true})
+	if _, err := session.Attach(ctx, 0, p9p.NOFID, "alice", "/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := session.Walk(ctx, 0, 1, "namespaces", "default", "deployments", "web", "dryrun"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := session.Open(ctx, 1, p9p.ORDWR); err != nil {
+		t.Fatalf("opening dryrun for writing: %v", err)
+	}
+	if _, err := session.Write(ctx, 1, []byte("spec:\n  replicas: 3\n"), 0); err != nil {
+		t.Fatalf("writing dryrun: %v", err)
+	}
+
+	if _, err := session.Walk(ctx, 0, 2, "namespaces", "default", "deployments", "web", "scale"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := session.Open(ctx, 2, p9p.OWRITE); err != p9p.ErrPerm {
+		t.Fatalf("got error %v opening scale for writing, want %v", err, p9p.ErrPerm)
+	}
+}
+
+func TestRestrictedListing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := kfake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db-1", Namespace: "default"}},
+	)
+
+	// The user may get the default namespace and the web-1 pod, and list
+	// nothing, so caches filled as the user would never sync.
+	user := kfake.NewSimpleClientset()
+	user.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(action.GetResource().GroupResource(), "", errors.New("list not allowed"))
+	})
+	user.PrependReactor("create", "selfsubjectrulesreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &authorizationv1.SelfSubjectRulesReview{Status: authorizationv1.SubjectRulesReviewStatus{
+			ResourceRules: []authorizationv1.ResourceRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"web-1"}}},
+		}}, nil
+	})
+	user.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		attributes := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview).Spec.ResourceAttributes
+		allowed := attributes.Verb == "get" && attributes.Resource == "namespaces" && attributes.Name == "default"
+		return true, &authorizationv1.SelfSubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed}}, nil
+	})
+
+	session := New(ctx, server, user, fake.NewSimpleDynamicClient(runtime.NewScheme()))
+	session.SetIdentity("bob")
+	if _, err := session.Attach(ctx, 0, p9p.NOFID, "bob", "/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := session.Walk(ctx, 0, 1, "namespaces", "default", "pods"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := session.Open(ctx, 1, p9p.OREAD); err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, 64*1024)
+	n, err := session.Read(ctx, 1, p, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	codec := p9p.NewCodec()
+	for reader := bytes.NewReader(p[:n]); reader.Len() > 0; {
+		var dir p9p.Dir
+		if err := p9p.DecodeDir(codec, reader, &dir); err != nil {
+			t.Fatal(err)
+		}
+		if dir.Name != "_table" {
+			names = append(names, dir.Name)
+		}
+	}
+	if len(names) != 1 || names[0] != "web-1" {
+		t.Errorf("got pods %v, want only web-1", names)
+	}
+
+	qids, err := session.Walk(ctx, 0, 2, "namespaces", "default", "pods", "db-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(qids) != 3 {
+		t.Errorf("walked %d elements to a hidden pod, want 3", len(qids))
+	}
+}
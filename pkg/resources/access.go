@@ -0,0 +1,224 @@
+package resources
+
+import (
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// accessTTL is how long access review results are cached for.
+const accessTTL = time.Minute
+
+type accessKey struct {
+	verb      string
+	resource  schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+type accessEntry struct {
+	allowed bool
+	expires time.Time
+}
+
+type rulesEntry struct {
+	rules      []authorizationv1.ResourceRule
+	incomplete bool
+	expires    time.Time
+}
+
+// Access answers whether the user of a client may perform an action. The user
+// is whoever the client authenticates as: the impersonated user of a TLS
+// client certificate, or otherwise the credentials k9p runs with, never the
+// user named in the attach request. The client should be that of the
+// session's user, even where the informer caches are filled with k9p's own
+// credentials.
+//
+// Within namespaces, it evaluates the rules of a SelfSubjectRulesReview, so
+// one request answers for every object in the namespace. When the rules are
+// incomplete, or for cluster-scoped resources, it falls back to a
+// SelfSubjectAccessReview for each action. Results are cached for accessTTL.
+//
+// Access is used to hide objects the user can't read and to set mode bits;
+// the API server remains responsible for enforcing access.
+type Access struct {
+	client kubernetes.Interface
+
+	mu      sync.Mutex
+	reviews map[accessKey]accessEntry
+	rules   map[string]rulesEntry
+	pruned  time.Time
+}
+
+func NewAccess(client kubernetes.Interface) *Access {
+	return &Access{
+		client:  client,
+		reviews: make(map[accessKey]accessEntry),
+		rules:   make(map[string]rulesEntry),
+	}
+}
+
+// Allowed reports if verb is allowed on the named object of resource, or on
+// all objects of resource if name is empty. If the user's access can't be
+// determined, the action is assumed to be allowed.
+func (a *Access) Allowed(verb string, resource schema.GroupVersionResource, namespace string, name string) bool {
+	if allowed, ok := a.ruled(verb, resource, namespace, name); ok {
+		return allowed
+	}
+
+	// An action allowed on all objects is allowed on each of them, which
+	// saves reviewing objects one by one.
+	key := accessKey{verb: verb, resource: resource, namespace: namespace}
+	if a.review(key) {
+		return true
+	}
+	if name == "" {
+		return false
+	}
+
+	key.name = name
+	return a.review(key)
+}
+
+// AllowedListing reports if verb is allowed on the named object of resource
+// like Allowed, but without reviewing the object on its own. When the rules
+// of the namespace don't answer, it reports if verb is allowed on all objects
+// of resource. Listings use it, so that listing a directory costs a few
+// reviews rather than one for each object.
+func (a *Access) AllowedListing(verb string, resource schema.GroupVersionResource, namespace string, name string) bool {
+	if allowed, ok := a.ruled(verb, resource, namespace, name); ok {
+		return allowed
+	}
+
+	return a.review(accessKey{verb: verb, resource: resource, namespace: namespace})
+}
+
+// ruled reports if verb is allowed on the named object of resource by the
+// rules of its namespace, and whether the rules are complete enough to tell.
+func (a *Access) ruled(verb string, resource schema.GroupVersionResource, namespace string, name string) (allowed bool, ok bool) {
+	if namespace == "" {
+		return false, false
+	}
+
+	entry, err := a.namespaceRules(namespace)
+	if err != nil || entry.incomplete {
+		return false, false
+	}
+
+	for _, rule := range entry.rules {
+		if ruleAllows(rule, verb, resource, name) {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+func (a *Access) namespaceRules(namespace string) (rulesEntry, error) {
+	a.mu.Lock()
+	entry, ok := a.rules[namespace]
+	a.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry, nil
+	}
+
+	review, err := a.client.AuthorizationV1().SelfSubjectRulesReviews().Create(&authorizationv1.SelfSubjectRulesReview{
+		Spec: authorizationv1.SelfSubjectRulesReviewSpec{
+			Namespace: namespace,
+		},
+	})
+	if err != nil {
+		return rulesEntry{}, err
+	}
+
+	entry = rulesEntry{
+		rules:      review.Status.ResourceRules,
+		incomplete: review.Status.Incomplete,
+		expires:    time.Now().Add(accessTTL),
+	}
+
+	a.mu.Lock()
+	a.prune()
+	a.rules[namespace] = entry
+	a.mu.Unlock()
+
+	return entry, nil
+}
+
+func (a *Access) review(key accessKey) bool {
+	a.mu.Lock()
+	entry, ok := a.reviews[key]
+	a.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.allowed
+	}
+
+	review, err := a.client.AuthorizationV1().SelfSubjectAccessReviews().Create(&authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:      key.verb,
+				Group:     key.resource.Group,
+				Version:   key.resource.Version,
+				Resource:  key.resource.Resource,
+				Namespace: key.namespace,
+				Name:      key.name,
+			},
+		},
+	})
+	if err != nil {
+		return true
+	}
+
+	a.mu.Lock()
+	a.prune()
+	a.reviews[key] = accessEntry{
+		allowed: review.Status.Allowed,
+		expires: time.Now().Add(accessTTL),
+	}
+	a.mu.Unlock()
+
+	return review.Status.Allowed
+}
+
+// prune removes expired results, so objects that are gone don't stay cached.
+// It sweeps at most once every accessTTL, and must be called with mu held.
+func (a *Access) prune() {
+	now := time.Now()
+	if now.Sub(a.pruned) < accessTTL {
+		return
+	}
+	a.pruned = now
+
+	for key, entry := range a.reviews {
+		if !now.Before(entry.expires) {
+			delete(a.reviews, key)
+		}
+	}
+	for namespace, entry := range a.rules {
+		if !now.Before(entry.expires) {
+			delete(a.rules, namespace)
+		}
+	}
+}
+
+// ruleAllows reports if rule allows verb on the named object of resource. A
+// rule limited to some resource names never allows access to all objects.
+func ruleAllows(rule authorizationv1.ResourceRule, verb string, resource schema.GroupVersionResource, name string) bool {
+	if !matches(rule.Verbs, verb) || !matches(rule.APIGroups, resource.Group) || !matches(rule.Resources, resource.Resource) {
+		return false
+	}
+
+	return len(rule.ResourceNames) == 0 || (name != "" && matches(rule.ResourceNames, name))
+}
+
+func matches(values []string, value string) bool {
+	for _, v := range values {
+		if v == "*" || v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package resources
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newReviewingAccess returns an Access whose namespace rules are incomplete,
+// so every question is answered by an access review, and a function
+// returning the number of reviews made.
+func newReviewingAccess(allowed func(attributes *authorizationv1.ResourceAttributes) bool) (*Access, func() int) {
+	reviews := 0
+
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectrulesreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &authorizationv1.SelfSubjectRulesReview{
+			Status: authorizationv1.SubjectRulesReviewStatus{Incomplete: true},
+		}, nil
+	})
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		return true, &authorizationv1.SelfSubjectAccessReview{
+			Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed(review.Spec.ResourceAttributes)},
+		}, nil
+	})
+
+	return NewAccess(client), func() int { return reviews }
+}
+
+func TestAccessListingReviews(t *testing.T) {
+	access, reviews := newReviewingAccess(func(attributes *authorizationv1.ResourceAttributes) bool {
+		return attributes.Verb == "get"
+	})
+
+	// Listing many objects costs a single review, rather than one for each.
+	for i := 0; i < 100; i++ {
+		if !access.AllowedListing("get", podsResource, "default", fmt.Sprintf("web-%d", i)) {
+			t.Fatalf("get of web-%d not allowed", i)
+		}
+		if access.AllowedListing("update", podsResource, "default", fmt.Sprintf("web-%d", i)) {
+			t.Fatalf("update of web-%d allowed", i)
+		}
+	}
+	if got := reviews(); got != 2 {
+		t.Errorf("got %d reviews listing objects, want 2", got)
+	}
+
+	// Objects are reviewed on their own only when the action isn't allowed
+	// on all of them.
+	if !access.Allowed("get", podsResource, "default", "web-200") {
+		t.Error("get of web-200 not allowed")
+	}
+	if got := reviews(); got != 2 {
+		t.Errorf("got %d reviews, want the action allowed on all objects to be reused", got)
+	}
+	if access.Allowed("update", podsResource, "default", "web-200") {
+		t.Error("update of web-200 allowed")
+	}
+	if got := reviews(); got != 3 {
+		t.Errorf("got %d reviews, want the object reviewed", got)
+	}
+}
+
+func TestAccessPrune(t *testing.T) {
+	access, _ := newReviewingAccess(func(attributes *authorizationv1.ResourceAttributes) bool {
+		return true
+	})
+
+	expired := time.Now().Add(-time.Second)
+	access.reviews[accessKey{verb: "get", resource: podsResource, namespace: "default", name: "gone"}] = accessEntry{expires: expired}
+	access.rules["gone"] = rulesEntry{expires: expired}
+
+	access.Allowed("get", nodesResource, "", "node-1")
+
+	if len(access.rules) != 0 {
+		t.Errorf("got rules of namespaces %v, want expired rules removed", access.rules)
+	}
+	for key := range access.reviews {
+		if key.name == "gone" {
+			t.Error("expired review was not removed")
+		}
+	}
+}
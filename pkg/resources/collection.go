@@ -0,0 +1,331 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/go-p9p"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// Collection is a directory of objects of a single resource type, such as
+// the deployments within a namespace.
+//
+// Walking to a name starting with @ selects the objects matching the label
+// selector following it, so pods/@app=web,tier!=cache is a collection of only
+// those pods. Similarly, names starting with % select by field selector, as
+// in pods/%status.phase=Failed.
+//
+// Objects the user may not get are hidden from listings and walks.
+//
+// Every collection also contains a _table file, summarising its objects with
+// a line of tab separated columns each, like kubectl get. Object names can't
+// contain underscores, so the file never hides an object.
+type Collection struct {
+	name      string
+	namespace string
+	resource  schema.GroupVersionResource
+	session   Session
+	list      func(selector labels.Selector) ([]Object, error)
+	get       func(name string) (Object, error)
+	newRef    func(object Object) Ref
+	columns   []column
+	qid       *p9p.Qid
+	info      *p9p.Dir
+	readdir   *p9p.Readdir
+}
+
+// filter returns a copy of the collection containing only the objects accepted
+// by keep.
+func (r *Collection) filter(keep func(object Object) bool) *Collection {
+	return &Collection{
+		name:      r.name,
+		namespace: r.namespace,
+		resource:  r.resource,
+		session:   r.session,
+		list: func(selector labels.Selector) ([]Object, error) {
+			objects, err := r.list(selector)
+			if err != nil {
+				return nil, err
+			}
+
+			kept := objects[:0]
+			for _, object := range objects {
+				if keep(object) {
+					kept = append(kept, object)
+				}
+			}
+			return kept, nil
+		},
+		get: func(name string) (Object, error) {
+			object, err := r.get(name)
+			if err != nil {
+				return nil, err
+			}
+			if !keep(object) {
+				return nil, p9p.ErrNotfound
+			}
+
+			return object, nil
+		},
+		newRef:  r.newRef,
+		columns: r.columns,
+	}
+}
+
+// selected returns a copy of the collection named name, containing only the
+// objects matching selector.
+func (r *Collection) selected(name string, selector labels.Selector) *Collection {
+	return &Collection{
+		name:      name,
+		namespace: r.namespace,
+		resource:  r.resource,
+		session:   r.session,
+		list: func(s labels.Selector) ([]Object, error) {
+			combined := selector
+			if requirements, selectable := s.Requirements(); selectable {
+				combined = selector.Add(requirements...)
+			}
+
+			return r.list(combined)
+		},
+		get: func(name string) (Object, error) {
+			object, err := r.get(name)
+			if err != nil {
+				return nil, err
+			}
+			if !selector.Matches(labels.Set(object.GetLabels())) {
+				return nil, p9p.ErrNotfound
+			}
+
+			return object, nil
+		},
+		newRef:  r.newRef,
+		columns: r.columns,
+	}
+}
+
+// column is a column of the _table file, between the name and age of each
+// object.
+type column struct {
+	name  string
+	value func(object Object) string
+}
+
+// visible returns the objects in the collection the user may get. Objects
+// aren't reviewed one by one, so when the user may not list the collection,
+// objects they may only get by name are left out unless the rules of the
+// namespace grant them.
+func (r *Collection) visible() ([]Object, error) {
+	objects, err := r.list(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	access := r.session.Access()
+	if access.Allowed("list", r.resource, r.namespace, "") {
+		return objects, nil
+	}
+
+	kept := objects[:0]
+	for _, object := range objects {
+		if access.AllowedListing("get", r.resource, object.GetNamespace(), object.GetName()) {
+			kept = append(kept, object)
+		}
+	}
+	return kept, nil
+}
+
+// newSummary returns the _table file of the collection.
+func (r *Collection) newSummary() *Dynamic {
+	return &Dynamic{
+		name:    "_table",
+		session: r.session,
+		generate: func(ctx context.Context) ([]byte, error) {
+			objects, err := r.visible()
+			if err != nil {
+				return nil, err
+			}
+
+			sort.Slice(objects, func(i, j int) bool {
+				return objects[i].GetName() < objects[j].GetName()
+			})
+
+			var buf bytes.Buffer
+			buf.WriteString("NAME")
+			for _, c := range r.columns {
+				buf.WriteString("\t" + c.name)
+			}
+			buf.WriteString("\tAGE\n")
+
+			now := time.Now()
+			for _, object := range objects {
+				buf.WriteString(object.GetName())
+				for _, c := range r.columns {
+					buf.WriteString("\t" + c.value(object))
+				}
+				buf.WriteString("\t" + duration.HumanDuration(now.Sub(object.GetCreationTimestamp().Time)) + "\n")
+			}
+
+			return buf.Bytes(), nil
+		},
+	}
+}
+
+// Path returns the directory of the collection's resource in its namespace,
+// or in the cluster directory. Collections of a namespaced resource across
+// every namespace, such as the owned pods of a node, have no such directory.
+func (r *Collection) Path() string {
+	if r.namespace == "" && r.resource != nodesResource {
+		return ""
+	}
+
+	return resourcePath(r.resource, r.namespace)
+}
+
+// Qid returns the qid of the collection, without finding its modification
+// time from every object as Info does.
+func (r *Collection) Qid() p9p.Qid {
+	if r.qid == nil {
+		r.qid = &p9p.Qid{
+			Type: p9p.QTDIR,
+			Path: rand.Uint64(),
+		}
+	}
+
+	return *r.qid
+}
+
+func (r *Collection) Info() p9p.Dir {
+	if r.info != nil {
+		return *r.info
+	}
+
+	dir := p9p.Dir{}
+	dir.Qid = r.Qid()
+
+	dir.Name = r.name
+	dir.Mode = modeDir
+	if r.session.Access().Allowed("delete", r.resource, r.namespace, "") {
+		dir.Mode |= modeWritable
+	}
+	dir.Length = 0
+	dir.ModTime = r.lastModified()
+	dir.AccessTime = dir.ModTime
+	setOwnership(&dir, nil, r.session)
+
+	dir.Mode |= p9p.DMDIR
+	r.info = &dir
+
+	return dir
+}
+
+// lastModified returns when the most recently changed object in the
+// collection last changed, or the current time if it is empty.
+func (r *Collection) lastModified() time.Time {
+	objects, err := r.list(labels.Everything())
+	if err != nil || len(objects) == 0 {
+		return time.Now()
+	}
+
+	var modified time.Time
+	for _, object := range objects {
+		if t := lastModified(object); t.After(modified) {
+			modified = t
+		}
+	}
+
+	return modified
+}
+
+func (r *Collection) Get(name string) (Ref, error) {
+	if name == "_table" {
+		return r.newSummary(), nil
+	}
+
+	// Selectors are path escaped, as keys such as app.kubernetes.io/name
+	// contain slashes.
+	if strings.HasPrefix(name, "@") {
+		query, err := url.PathUnescape(name[1:])
+		if err != nil {
+			return nil, p9p.ErrNotfound
+		}
+		selector, err := labels.Parse(query)
+		if err != nil {
+			return nil, p9p.ErrNotfound
+		}
+
+		return r.selected(name, selector), nil
+	}
+
+	if strings.HasPrefix(name, "%") {
+		query, err := url.PathUnescape(name[1:])
+		if err != nil {
+			return nil, p9p.ErrNotfound
+		}
+		selector, err := fields.ParseSelector(query)
+		if err != nil {
+			return nil, p9p.ErrNotfound
+		}
+
+		selected := r.filter(func(object Object) bool {
+			return matchesFields(object, selector)
+		})
+		selected.name = name
+		return selected, nil
+	}
+
+	object, err := r.get(name)
+	if apierrors.IsNotFound(err) {
+		return nil, p9p.ErrNotfound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.session.Access().Allowed("get", r.resource, object.GetNamespace(), object.GetName()) {
+		return nil, p9p.ErrNotfound
+	}
+
+	return r.newRef(object), nil
+}
+
+func (r *Collection) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	if r.readdir != nil {
+		return r.readdir.Read(ctx, p, offset)
+	}
+
+	objects, err := r.visible()
+	if err != nil {
+		return 0, err
+	}
+
+	refs := make([]Ref, 0, len(objects)+1)
+	refs = append(refs, r.newSummary())
+	for _, object := range objects {
+		refs = append(refs, r.newRef(object))
+	}
+
+	r.readdir = p9p.NewReaddir(p9p.NewCodec(), func() (p9p.Dir, error) {
+		if len(refs) == 0 {
+			return p9p.Dir{}, io.EOF
+		}
+
+		ref := refs[0]
+		refs = refs[1:]
+
+		return ref.Info(), nil
+	})
+
+	return r.readdir.Read(ctx, p, offset)
+}
@@ -0,0 +1,116 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/docker/go-p9p"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// allowAll returns an Access allowing every action.
+func allowAll() *Access {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectrulesreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &authorizationv1.SelfSubjectRulesReview{
+			Status: authorizationv1.SubjectRulesReviewStatus{
+				ResourceRules: []authorizationv1.ResourceRule{{
+					Verbs:     []string{"*"},
+					APIGroups: []string{"*"},
+					Resources: []string{"*"},
+				}},
+			},
+		}, nil
+	})
+
+	return NewAccess(client)
+}
+
+// listNames returns the names of the objects listed in a collection.
+func listNames(t *testing.T, collection *Collection) []string {
+	t.Helper()
+
+	p := make([]byte, 64*1024)
+	n, err := collection.Read(context.Background(), p, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	codec := p9p.NewCodec()
+	for reader := bytes.NewReader(p[:n]); reader.Len() > 0; {
+		var dir p9p.Dir
+		if err := p9p.DecodeDir(codec, reader, &dir); err != nil {
+			t.Fatal(err)
+		}
+
+		if dir.Name != "_table" {
+			names = append(names, dir.Name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func testPod(name string, labels map[string]string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}}
+}
+
+func TestCollectionSelectors(t *testing.T) {
+	session := newTestSession(t,
+		testPod("web-1", map[string]string{"app.kubernetes.io/name": "web"}),
+		testPod("web-2", map[string]string{"app.kubernetes.io/name": "web"}),
+		testPod("db-1", map[string]string{"app.kubernetes.io/name": "db"}),
+	)
+	session.access = allowAll()
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "@app.kubernetes.io%2Fname=web", want: []string{"web-1", "web-2"}},
+		{name: "@app.kubernetes.io%2Fname%20in%20(db)", want: []string{"db-1"}},
+		{name: "%metadata.name=db-1", want: []string{"db-1"}},
+		{name: "%metadata.name!%3Ddb-1", want: []string{"web-1", "web-2"}},
+	}
+	for _, test := range tests {
+		ref, err := NewPods("default", session).Get(test.name)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		got := listNames(t, ref.(*Collection))
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCollectionInvalidSelectors(t *testing.T) {
+	session := newTestSession(t)
+	session.access = allowAll()
+
+	for _, name := range []string{
+		// Malformed escapes.
+		"@app%zz",
+		"%metadata.name=%",
+		// Malformed selectors.
+		"@app in (web",
+		"@app.kubernetes.io%2F=web",
+		"%metadata.name",
+	} {
+		if _, err := NewPods("default", session).Get(name); err != p9p.ErrNotfound {
+			t.Errorf("%s: got error %v, want %v", name, err, p9p.ErrNotfound)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+var cronJobsResource = v1beta1.SchemeGroupVersion.WithResource("cronjobs")
+
+func NewCronJobs(namespace string, session Session) *Collection {
+	lister := session.Informer().Batch().V1beta1().CronJobs().Lister().CronJobs(namespace)
+	return &Collection{
+		name:      "cronjobs",
+		namespace: namespace,
+		resource:  cronJobsResource,
+		session:   session,
+		list: func(selector labels.Selector) ([]Object, error) {
+			cronJobs, err := lister.List(selector)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]Object, 0, len(cronJobs))
+			for _, cronJob := range cronJobs {
+				objects = append(objects, cronJob)
+			}
+			return objects, nil
+		},
+		get: func(name string) (Object, error) {
+			return lister.Get(name)
+		},
+		newRef: func(object Object) Ref {
+			return NewCronJobRef(object.(*v1beta1.CronJob), session)
+		},
+		columns: cronJobColumns,
+	}
+}
+
+// cronJobColumns are the _table columns of cronjobs, following kubectl get
+// cronjobs.
+var cronJobColumns = []column{
+	{"SCHEDULE", func(object Object) string {
+		return object.(*v1beta1.CronJob).Spec.Schedule
+	}},
+	{"SUSPEND", func(object Object) string {
+		suspend := object.(*v1beta1.CronJob).Spec.Suspend
+		return fmt.Sprint(suspend != nil && *suspend)
+	}},
+	{"ACTIVE", func(object Object) string {
+		return fmt.Sprint(len(object.(*v1beta1.CronJob).Status.Active))
+	}},
+	{"LAST SCHEDULE", func(object Object) string {
+		last := object.(*v1beta1.CronJob).Status.LastScheduleTime
+		if last == nil {
+			return "<none>"
+		}
+		return duration.HumanDuration(time.Since(last.Time))
+	}},
+}
+
+// NewCronJobRef returns the directory of a CronJob. The ctl file accepts the
+// commands:
+//
+//	trigger	create a Job from the jobTemplate, like kubectl create job --from
+//	suspend	stop scheduling new Jobs
+//	resume	resume scheduling new Jobs
+func NewCronJobRef(cronJob *v1beta1.CronJob, session Session) *ObjectRef {
+	suspended := cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+
+	return NewObjectRef(cronJob, cronJobsResource, session, map[string]Ref{
+		"ctl": &Ctl{
+			name:    "ctl",
+			content: []byte(fmt.Sprintf("schedule %s\nsuspend %t\n", cronJob.Spec.Schedule, suspended)),
+			object:  cronJob,
+			session: session,
+			write: func(ctx context.Context, p []byte) error {
+				return cronJobCtl(ctx, cronJob, session, string(p))
+			},
+		},
+		"jobs": newOwnedJobs(cronJob, session),
+	})
+}
+
+func cronJobCtl(ctx context.Context, cronJob *v1beta1.CronJob, session Session, cmd string) error {
+	switch cmd {
+	case "trigger":
+		return createObject(ctx, session, jobsResource, cronJob.Namespace, jobFromCronJob(cronJob))
+	case "suspend":
+		return patchObject(ctx, session, cronJobsResource, cronJob.Namespace, cronJob.Name, []byte(`{"spec":{"suspend":true}}`))
+	case "resume":
+		return patchObject(ctx, session, cronJobsResource, cronJob.Namespace, cronJob.Name, []byte(`{"spec":{"suspend":false}}`))
+	}
+
+	return fmt.Errorf("unknown ctl command %q", cmd)
+}
+
+// jobFromCronJob creates a Job from the jobTemplate of cronJob, as done by
+// kubectl create job --from.
+func jobFromCronJob(cronJob *v1beta1.CronJob) *batchv1.Job {
+	annotations := map[string]string{
+		"cronjob.kubernetes.io/instantiate": "manual",
+	}
+	for k, v := range cronJob.Spec.JobTemplate.Annotations {
+		annotations[k] = v
+	}
+
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+			Kind:       "Job",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-manual-%s", cronJob.Name, rand.String(5)),
+			Namespace:   cronJob.Namespace,
+			Labels:      cronJob.Spec.JobTemplate.Labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cronJob, v1beta1.SchemeGroupVersion.WithKind("CronJob")),
+			},
+		},
+		Spec: cronJob.Spec.JobTemplate.Spec,
+	}
+}
@@ -0,0 +1,211 @@
+package resources
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/go-p9p"
+	"k8s.io/api/batch/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// apiRequest is a request received by a test API server.
+type apiRequest struct {
+	method      string
+	path        string
+	query       string
+	contentType string
+	body        string
+}
+
+// serveTestAPI points the clients of session at an API server that records
+// the requests it receives and responds to each with the body returned by
+// respond.
+func serveTestAPI(t *testing.T, session *testSession, respond func(request apiRequest) string) (func() []apiRequest, func()) {
+	var (
+		mu       sync.Mutex
+		requests []apiRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		request := apiRequest{
+			method:      r.Method,
+			path:        r.URL.Path,
+			query:       r.URL.RawQuery,
+			contentType: r.Header.Get("Content-Type"),
+			body:        string(body),
+		}
+		mu.Lock()
+		requests = append(requests, request)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(respond(request)))
+	}))
+
+	config := &rest.Config{Host: server.URL}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	session.client = client
+
+	return func() []apiRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]apiRequest(nil), requests...)
+	}, server.Close
+}
+
+// respondWith returns a response function of serveTestAPI responding with
+// body to every request.
+func respondWith(body string) func(request apiRequest) string {
+	return func(request apiRequest) string {
+		return body
+	}
+}
+
+func testCronJob() *v1beta1.CronJob {
+	return &v1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default", UID: "backup-uid"},
+		Spec: v1beta1.CronJobSpec{
+			Schedule: "0 * * * *",
+			JobTemplate: v1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      map[string]string{"app": "backup"},
+					Annotations: map[string]string{"team": "storage"},
+				},
+			},
+		},
+	}
+}
+
+func TestJobFromCronJob(t *testing.T) {
+	cronJob := testCronJob()
+	cronJob.Spec.JobTemplate.Spec.Template.Spec.RestartPolicy = v1.RestartPolicyOnFailure
+
+	job := jobFromCronJob(cronJob)
+
+	if !strings.HasPrefix(job.Name, "backup-manual-") || len(job.Name) != len("backup-manual-")+5 {
+		t.Errorf("got name %q, want backup-manual- and a random suffix", job.Name)
+	}
+	if job.Namespace != "default" {
+		t.Errorf("got namespace %q, want default", job.Namespace)
+	}
+	if job.Labels["app"] != "backup" {
+		t.Errorf("got labels %v, want those of the job template", job.Labels)
+	}
+	if job.Annotations["team"] != "storage" || job.Annotations["cronjob.kubernetes.io/instantiate"] != "manual" {
+		t.Errorf("got annotations %v, want those of the job template and the manual instantiation", job.Annotations)
+	}
+	if job.Spec.Template.Spec.RestartPolicy != v1.RestartPolicyOnFailure {
+		t.Errorf("got restart policy %q, want the job template's", job.Spec.Template.Spec.RestartPolicy)
+	}
+
+	owner := metav1.GetControllerOf(job)
+	if owner == nil || owner.Kind != "CronJob" || owner.Name != "backup" || owner.UID != "backup-uid" {
+		t.Errorf("got controller %v, want the cronjob", owner)
+	}
+
+	// The job template's annotations are left unchanged.
+	if _, ok := cronJob.Spec.JobTemplate.Annotations["cronjob.kubernetes.io/instantiate"]; ok {
+		t.Error("creating a job changed the annotations of the job template")
+	}
+}
+
+func TestCronJobCtl(t *testing.T) {
+	tests := []struct {
+		command     string
+		method      string
+		path        string
+		contentType string
+		body        string
+	}{
+		{
+			command:     "suspend",
+			method:      "PATCH",
+			path:        "/apis/batch/v1beta1/namespaces/default/cronjobs/backup",
+			contentType: "application/merge-patch+json",
+			body:        `{"spec":{"suspend":true}}`,
+		},
+		{
+			command:     "resume",
+			method:      "PATCH",
+			path:        "/apis/batch/v1beta1/namespaces/default/cronjobs/backup",
+			contentType: "application/merge-patch+json",
+			body:        `{"spec":{"suspend":false}}`,
+		},
+		{
+			command: "trigger",
+			method:  "POST",
+			path:    "/apis/batch/v1/namespaces/default/jobs",
+		},
+	}
+
+	for _, test := range tests {
+		session := newTestSession(t)
+		requests, closeServer := serveTestAPI(t, session, respondWith("{}"))
+
+		err := cronJobCtl(context.Background(), testCronJob(), session, test.command)
+		got := requests()
+		closeServer()
+
+		if err != nil {
+			t.Errorf("%s: %v", test.command, err)
+			continue
+		}
+
+		if len(got) != 1 {
+			t.Errorf("%s: got %d requests, want 1", test.command, len(got))
+			continue
+		}
+		if got[0].method != test.method || got[0].path != test.path {
+			t.Errorf("%s: got %s %s, want %s %s", test.command, got[0].method, got[0].path, test.method, test.path)
+		}
+		if test.contentType != "" && got[0].contentType != test.contentType {
+			t.Errorf("%s: got content type %q, want %q", test.command, got[0].contentType, test.contentType)
+		}
+		if test.body != "" && got[0].body != test.body {
+			t.Errorf("%s: got body %s, want %s", test.command, got[0].body, test.body)
+		}
+		if test.command == "trigger" && !strings.Contains(got[0].body, `"name":"backup-manual-`) {
+			t.Errorf("trigger: got body %s, want a job created from the cronjob", got[0].body)
+		}
+	}
+}
+
+func TestCronJobCtlUnknownCommand(t *testing.T) {
+	session := newTestSession(t)
+	requests, closeServer := serveTestAPI(t, session, respondWith("{}"))
+	defer closeServer()
+
+	ctl, err := NewCronJobRef(testCronJob(), session).Get("ctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := ctl.(Opener).Open(context.Background(), p9p.OWRITE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opened.(Writer).Write(context.Background(), []byte("pause\n"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	err = opened.(Closer).Close(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `unknown ctl command "pause"`) {
+		t.Errorf("got error %v, want an unknown command error", err)
+	}
+	if got := requests(); len(got) != 0 {
+		t.Errorf("got requests %v for an unknown command", got)
+	}
+}
@@ -0,0 +1,95 @@
+package resources
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var daemonSetsResource = v1.SchemeGroupVersion.WithResource("daemonsets")
+
+func NewDaemonSets(namespace string, session Session) *Collection {
+	lister := session.Informer().Apps().V1().DaemonSets().Lister().DaemonSets(namespace)
+	return &Collection{
+		name:      "daemonsets",
+		namespace: namespace,
+		resource:  daemonSetsResource,
+		session:   session,
+		list: func(selector labels.Selector) ([]Object, error) {
+			daemonSets, err := lister.List(selector)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]Object, 0, len(daemonSets))
+			for _, daemonSet := range daemonSets {
+				objects = append(objects, daemonSet)
+			}
+			return objects, nil
+		},
+		get: func(name string) (Object, error) {
+			return lister.Get(name)
+		},
+		newRef: func(object Object) Ref {
+			return NewDaemonSetRef(object.(*v1.DaemonSet), session)
+		},
+		columns: daemonSetColumns,
+	}
+}
+
+// daemonSetColumns are the _table columns of daemonsets, following kubectl get
+// daemonsets.
+var daemonSetColumns = []column{
+	{"DESIRED", func(object Object) string {
+		return fmt.Sprint(object.(*v1.DaemonSet).Status.DesiredNumberScheduled)
+	}},
+	{"CURRENT", func(object Object) string {
+		return fmt.Sprint(object.(*v1.DaemonSet).Status.CurrentNumberScheduled)
+	}},
+	{"READY", func(object Object) string {
+		return fmt.Sprint(object.(*v1.DaemonSet).Status.NumberReady)
+	}},
+	{"UP-TO-DATE", func(object Object) string {
+		return fmt.Sprint(object.(*v1.DaemonSet).Status.UpdatedNumberScheduled)
+	}},
+	{"AVAILABLE", func(object Object) string {
+		return fmt.Sprint(object.(*v1.DaemonSet).Status.NumberAvailable)
+	}},
+}
+
+// NewDaemonSetRef returns the directory of a DaemonSet. DaemonSets are scaled
+// by their node selector rather than a replica count, so there is no scale
+// file.
+func NewDaemonSetRef(daemonSet *v1.DaemonSet, session Session) *ObjectRef {
+	return NewObjectRef(daemonSet, daemonSetsResource, session, map[string]Ref{
+		"rollout": &Static{
+			name:    "rollout",
+			content: []byte(daemonSetRolloutStatus(daemonSet)),
+			object:  daemonSet,
+			session: session,
+		},
+		"pods": newOwnedPods(daemonSet, session),
+	})
+}
+
+// daemonSetRolloutStatus describes the progress of a rollout, following the
+// messages of kubectl rollout status.
+func daemonSetRolloutStatus(daemonSet *v1.DaemonSet) string {
+	if daemonSet.Spec.UpdateStrategy.Type != v1.RollingUpdateDaemonSetStrategyType {
+		return fmt.Sprintf("rollout status is only available for %s strategy type\n", v1.RollingUpdateDaemonSetStrategyType)
+	}
+	if daemonSet.Generation > daemonSet.Status.ObservedGeneration {
+		return "Waiting for daemon set spec update to be observed...\n"
+	}
+
+	status := daemonSet.Status
+	switch {
+	case status.UpdatedNumberScheduled < status.DesiredNumberScheduled:
+		return fmt.Sprintf("Waiting for daemon set %q rollout to finish: %d out of %d new pods have been updated...\n", daemonSet.Name, status.UpdatedNumberScheduled, status.DesiredNumberScheduled)
+	case status.NumberAvailable < status.DesiredNumberScheduled:
+		return fmt.Sprintf("Waiting for daemon set %q rollout to finish: %d of %d updated pods are available...\n", daemonSet.Name, status.NumberAvailable, status.DesiredNumberScheduled)
+	}
+
+	return fmt.Sprintf("daemon set %q successfully rolled out\n", daemonSet.Name)
+}
@@ -1,184 +1,92 @@
 package resources
 
 import (
-	"context"
-	"io"
-	"math/rand"
-	"strconv"
-	"time"
+	"fmt"
 
-	"github.com/docker/go-p9p"
 	v1 "k8s.io/api/apps/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
-	appsv1 "k8s.io/client-go/informers/apps/v1"
-	"sigs.k8s.io/yaml"
 )
 
-type Deployments struct {
-	namespace          string
-	deploymentInformer appsv1.DeploymentInformer
-	session            Session
-	info               *p9p.Dir
-	readdir            *p9p.Readdir
-}
-
-func NewDeployments(namespace string, session Session) *Deployments {
-	deploymentInformer := session.Informer().Apps().V1().Deployments()
-	return &Deployments{
-		namespace:          namespace,
-		deploymentInformer: deploymentInformer,
-		session:            session,
-	}
-}
-
-func (r *Deployments) Info() p9p.Dir {
-	if r.info != nil {
-		return *r.info
-	}
-
-	dir := p9p.Dir{}
-	dir.Qid.Path = rand.Uint64()
-	dir.Qid.Version = 0
-
-	dir.Name = "deployments"
-	dir.Mode = 0664
-	dir.Length = 0
-	dir.AccessTime = time.Now()
-	dir.ModTime = time.Now()
-	dir.MUID = "none"
-
-	uname, _ := r.session.GetAuth()
-	dir.UID = uname
-	dir.GID = uname
-
-	dir.Qid.Type |= p9p.QTDIR
-	dir.Mode |= p9p.DMDIR
-	r.info = &dir
-
-	return dir
-}
-
-func (r *Deployments) Get(name string) (Ref, error) {
-	deployment, err := r.deploymentInformer.Lister().Deployments(r.namespace).Get(name)
-	if apierrors.IsNotFound(err) {
-		return nil, p9p.ErrNotfound
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	return NewDeploymentRef(deployment, r.session), nil
-}
-
-func (r *Deployments) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
-	if r.readdir != nil {
-		return r.readdir.Read(ctx, p, offset)
-	}
-
-	deployments, err := r.deploymentInformer.Lister().Deployments(r.namespace).List(labels.Everything())
-	if err != nil {
-		return 0, err
-	}
-
-	deploymentRefs := make([]Ref, 0, len(deployments))
-
-	for _, deployment := range deployments {
-		deployment := deployment
-		deploymentRefs = append(deploymentRefs, NewDeploymentRef(deployment, r.session))
+var deploymentsResource = v1.SchemeGroupVersion.WithResource("deployments")
+
+func NewDeployments(namespace string, session Session) *Collection {
+	lister := session.Informer().Apps().V1().Deployments().Lister().Deployments(namespace)
+	return &Collection{
+		name:      "deployments",
+		namespace: namespace,
+		resource:  deploymentsResource,
+		session:   session,
+		list: func(selector labels.Selector) ([]Object, error) {
+			deployments, err := lister.List(selector)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]Object, 0, len(deployments))
+			for _, deployment := range deployments {
+				objects = append(objects, deployment)
+			}
+			return objects, nil
+		},
+		get: func(name string) (Object, error) {
+			return lister.Get(name)
+		},
+		newRef: func(object Object) Ref {
+			return NewDeploymentRef(object.(*v1.Deployment), session)
+		},
+		columns: deploymentColumns,
 	}
-
-	r.readdir = p9p.NewReaddir(p9p.NewCodec(), func() (p9p.Dir, error) {
-		if len(deploymentRefs) == 0 {
-			return p9p.Dir{}, io.EOF
-		}
-
-		deployment := deploymentRefs[0]
-		deploymentRefs = deploymentRefs[1:]
-
-		return deployment.Info(), nil
-	})
-
-	n, err = r.readdir.Read(ctx, p, offset)
-
-	return n, err
 }
 
-type DeploymentRef struct {
-	deployment *v1.Deployment
-	session    Session
-	info       *p9p.Dir
-	readdir    *p9p.Readdir
-	children   map[string]Ref
+// deploymentColumns are the _table columns of deployments, following kubectl
+// get deployments.
+var deploymentColumns = []column{
+	{"READY", func(object Object) string {
+		deployment := object.(*v1.Deployment)
+		return fmt.Sprintf("%d/%d", deployment.Status.ReadyReplicas, *deployment.Spec.Replicas)
+	}},
+	{"UP-TO-DATE", func(object Object) string {
+		return fmt.Sprint(object.(*v1.Deployment).Status.UpdatedReplicas)
+	}},
+	{"AVAILABLE", func(object Object) string {
+		return fmt.Sprint(object.(*v1.Deployment).Status.AvailableReplicas)
+	}},
 }
 
-func NewDeploymentRef(deployment *v1.Deployment, session Session) *DeploymentRef {
-	y, _ := yaml.Marshal(deployment)
-	children := map[string]Ref{
-		"data.yaml": &Static{
-			name:    "data.yaml",
-			content: y,
-			session: session,
-		},
-		"scale": &Static{
-			name:    "scale",
-			content: []byte(strconv.Itoa(int(*deployment.Spec.Replicas))),
+func NewDeploymentRef(deployment *v1.Deployment, session Session) *ObjectRef {
+	return NewObjectRef(deployment, deploymentsResource, session, map[string]Ref{
+		"scale": newScaleCtl(deployment, deploymentsResource, *deployment.Spec.Replicas, session),
+		"rollout": &Static{
+			name:    "rollout",
+			content: []byte(deploymentRolloutStatus(deployment)),
+			object:  deployment,
 			session: session,
 		},
-	}
-	return &DeploymentRef{
-		deployment: deployment,
-		session:    session,
-		children:   children,
-	}
-}
-
-func (r *DeploymentRef) Info() p9p.Dir {
-	if r.info != nil {
-		return *r.info
-	}
-
-	dir := p9p.Dir{}
-	dir.Qid.Path = rand.Uint64()
-	dir.Qid.Version = 0
-
-	dir.Name = r.deployment.Name
-	dir.Mode = 0664
-	dir.Length = 0
-	dir.AccessTime = r.deployment.CreationTimestamp.Time
-	dir.ModTime = r.deployment.CreationTimestamp.Time
-	dir.MUID = "none"
-
-	uname, _ := r.session.GetAuth()
-	dir.UID = uname
-	dir.GID = uname
-
-	dir.Qid.Type |= p9p.QTDIR
-	dir.Mode |= p9p.DMDIR
-	r.info = &dir
-
-	return dir
+	})
 }
 
-func (r *DeploymentRef) Get(name string) (Ref, error) {
-	ref, ok := r.children[name]
-	if !ok {
-		return nil, p9p.ErrNotfound
+// deploymentRolloutStatus describes the progress of a rollout, following the
+// messages of kubectl rollout status.
+func deploymentRolloutStatus(deployment *v1.Deployment) string {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return "Waiting for deployment spec update to be observed...\n"
 	}
 
-	return ref, nil
-}
-
-func (r *DeploymentRef) Read(ctx context.Context, p []byte, offset int64) (int, error) {
-	if r.readdir != nil {
-		return r.readdir.Read(ctx, p, offset)
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == v1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return fmt.Sprintf("deployment %q exceeded its progress deadline\n", deployment.Name)
+		}
 	}
 
-	dir := make([]p9p.Dir, 0, len(r.children))
-	for _, child := range r.children {
-		dir = append(dir, child.Info())
+	status := deployment.Status
+	switch {
+	case status.UpdatedReplicas < *deployment.Spec.Replicas:
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d out of %d new replicas have been updated...\n", deployment.Name, status.UpdatedReplicas, *deployment.Spec.Replicas)
+	case status.Replicas > status.UpdatedReplicas:
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d old replicas are pending termination...\n", deployment.Name, status.Replicas-status.UpdatedReplicas)
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d of %d updated replicas are available...\n", deployment.Name, status.AvailableReplicas, status.UpdatedReplicas)
 	}
 
-	r.readdir = p9p.NewFixedReaddir(p9p.NewCodec(), dir)
-	return r.readdir.Read(ctx, p, offset)
+	return fmt.Sprintf("deployment %q successfully rolled out\n", deployment.Name)
 }
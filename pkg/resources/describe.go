@@ -0,0 +1,186 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// newDescribe returns a describe file with a human readable description of
+// object, in the style of kubectl describe: its metadata, the fields of its
+// spec and status, and the events involving it.
+func newDescribe(object Object, session Session) *Dynamic {
+	events := newObjectEvents(object, session)
+
+	return &Dynamic{
+		name:    "describe",
+		object:  object,
+		session: session,
+		generate: func(ctx context.Context) ([]byte, error) {
+			content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+			if err != nil {
+				return nil, err
+			}
+
+			var buf bytes.Buffer
+			w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+
+			fmt.Fprintf(w, "Name:\t%s\n", object.GetName())
+			if object.GetNamespace() != "" {
+				fmt.Fprintf(w, "Namespace:\t%s\n", object.GetNamespace())
+			}
+			describeMap(w, "Labels", object.GetLabels())
+			describeMap(w, "Annotations", object.GetAnnotations())
+			for _, ref := range object.GetOwnerReferences() {
+				if ref.Controller != nil && *ref.Controller {
+					fmt.Fprintf(w, "Controlled By:\t%s/%s\n", ref.Kind, ref.Name)
+				}
+			}
+			fmt.Fprintf(w, "CreationTimestamp:\t%s\n", object.GetCreationTimestamp().UTC().Format("Mon, 02 Jan 2006 15:04:05 -0700"))
+
+			keys := make([]string, 0, len(content))
+			for key := range content {
+				switch key {
+				case "apiVersion", "kind", "metadata":
+					continue
+				}
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				describeValue(w, 0, "", key, content[key])
+			}
+
+			list, err := events.list()
+			if err != nil {
+				return nil, err
+			}
+			if len(list) == 0 {
+				fmt.Fprintf(w, "Events:\t<none>\n")
+			} else {
+				fmt.Fprintf(w, "Events:\n")
+				fmt.Fprintf(w, "  Age\tType\tReason\tObject\tMessage\n")
+				now := time.Now()
+				for _, event := range list {
+					fmt.Fprintf(w, "  %s\t%s\t%s\t%s/%s\t%s\n",
+						duration.HumanDuration(now.Sub(eventTime(event))),
+						event.Type,
+						event.Reason,
+						strings.ToLower(event.InvolvedObject.Kind),
+						event.InvolvedObject.Name,
+						strings.TrimSpace(event.Message),
+					)
+				}
+			}
+
+			if err := w.Flush(); err != nil {
+				return nil, err
+			}
+
+			return buf.Bytes(), nil
+		},
+	}
+}
+
+// describeMap writes a labels or annotations field, with one key=value pair
+// per line.
+func describeMap(w io.Writer, name string, values map[string]string) {
+	if len(values) == 0 {
+		fmt.Fprintf(w, "%s:\t<none>\n", name)
+		return
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for i, key := range keys {
+		label := name + ":"
+		if i > 0 {
+			label = ""
+		}
+		fmt.Fprintf(w, "%s\t%s=%s\n", label, key, values[key])
+	}
+}
+
+// describeValue writes a field of an unstructured object, nesting maps and
+// lists beneath their key. Items of a list are marked by prefix.
+func describeValue(w io.Writer, indent int, prefix string, key string, value interface{}) {
+	label := strings.Repeat(" ", indent) + prefix + describeLabel(key) + ":"
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			fmt.Fprintf(w, "%s\t<none>\n", label)
+			return
+		}
+
+		fmt.Fprintf(w, "%s\n", label)
+		describeFields(w, indent+2, "", value)
+	case []interface{}:
+		if len(value) == 0 {
+			fmt.Fprintf(w, "%s\t<none>\n", label)
+			return
+		}
+
+		fmt.Fprintf(w, "%s\n", label)
+		for _, item := range value {
+			if fields, ok := item.(map[string]interface{}); ok {
+				describeFields(w, indent+2, "- ", fields)
+				continue
+			}
+			fmt.Fprintf(w, "%s- %v\n", strings.Repeat(" ", indent+2), item)
+		}
+	case nil:
+		fmt.Fprintf(w, "%s\t<none>\n", label)
+	default:
+		fmt.Fprintf(w, "%s\t%v\n", label, value)
+	}
+}
+
+// describeFields writes the fields of a map in key order. The first field is
+// marked by prefix, and the rest are aligned with it.
+func describeFields(w io.Writer, indent int, prefix string, fields map[string]interface{}) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for i, key := range keys {
+		p := prefix
+		if i > 0 {
+			p = strings.Repeat(" ", len(prefix))
+		}
+		describeValue(w, indent, p, key, fields[key])
+	}
+}
+
+// describeLabel turns a field name like nodeName into a label like Node Name.
+func describeLabel(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		if i == 0 {
+			b.WriteRune(unicode.ToUpper(r))
+			continue
+		}
+		if unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,196 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/docker/go-p9p"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// DryRun is a file previewing changes to an object. A manifest written to the
+// file is submitted as an update with dryRun=All, and reading the same fid
+// returns a unified diff from the live object to the result, followed by the
+// resulting object. Nothing is changed on the server.
+//
+// Each open fid has its own manifest, so concurrent previews don't mix.
+type DryRun struct {
+	object   Object
+	resource schema.GroupVersionResource
+	session  Session
+	manifest []byte
+	content  []byte
+	info     *p9p.Dir
+}
+
+func newDryRun(object Object, resource schema.GroupVersionResource, session Session) *DryRun {
+	return &DryRun{
+		object:   object,
+		resource: resource,
+		session:  session,
+	}
+}
+
+func (r *DryRun) Info() p9p.Dir {
+	if r.info != nil {
+		return *r.info
+	}
+
+	dir := p9p.Dir{}
+	dir.Qid.Path = rand.Uint64()
+	dir.Qid.Version = 0
+
+	dir.Name = "dryrun"
+	dir.Mode = modeReadWrite
+	dir.Length = 0
+	dir.AccessTime = time.Now()
+	dir.ModTime = time.Now()
+	setOwnership(&dir, r.object, r.session)
+
+	dir.Qid.Type |= p9p.QTFILE
+
+	r.info = &dir
+	return dir
+}
+
+func (r *DryRun) Get(name string) (Ref, error) {
+	return nil, p9p.ErrWalknodir
+}
+
+// Open returns a DryRun for the opened fid.
+func (r *DryRun) Open(ctx context.Context, mode p9p.Flag) (Ref, error) {
+	return newDryRun(r.object, r.resource, r.session), nil
+}
+
+// Previews reports that writes are only submitted as dry runs.
+func (r *DryRun) Previews() bool {
+	return true
+}
+
+func (r *DryRun) Write(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	// Writing from the start begins a new manifest.
+	if offset == 0 {
+		r.manifest = r.manifest[:0]
+	}
+	if offset < 0 || offset != int64(len(r.manifest)) {
+		return 0, p9p.ErrBadoffset
+	}
+
+	r.manifest = append(r.manifest, p...)
+	r.content = nil
+
+	return len(p), nil
+}
+
+func (r *DryRun) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	if r.content == nil && len(r.manifest) > 0 {
+		content, err := r.submit(ctx)
+		if err != nil {
+			return 0, err
+		}
+		r.content = content
+	}
+
+	return readAt(p, r.content, offset)
+}
+
+// submit updates the object with the manifest as a dry run, returning the
+// diff from the live object followed by the result. The diff leaves out the
+// status and server managed metadata, as spec.yaml does, so it shows only
+// the changes the manifest makes.
+func (r *DryRun) submit(ctx context.Context) ([]byte, error) {
+	live, err := getObject(ctx, r.session, r.resource, r.object.GetNamespace(), r.object.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := parseManifest(r.manifest, live)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := updateObject(ctx, r.session, r.resource, manifest, true)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := yaml.Marshal(cleanObject(live).Object)
+	if err != nil {
+		return nil, err
+	}
+	after, err := yaml.Marshal(cleanObject(result).Object)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(before)),
+		B:        difflib.SplitLines(string(after)),
+		FromFile: "live",
+		ToFile:   "dryrun",
+		Context:  3,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := yaml.Marshal(result.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(diff)
+	buf.WriteString("\n")
+	buf.Write(stored)
+
+	return buf.Bytes(), nil
+}
+
+// parseManifest parses a YAML manifest updating live. Fields identifying the
+// object may be left out, and are taken from live, as are the resourceVersion
+// and owner references if not given. Owner references can be removed by
+// giving an empty list.
+func parseManifest(data []byte, live *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	var manifest unstructured.Unstructured
+	if err := yaml.Unmarshal(data, &manifest.Object); err != nil {
+		return nil, err
+	}
+	if manifest.Object == nil {
+		return nil, errors.New("empty manifest")
+	}
+
+	if manifest.GetName() == "" {
+		manifest.SetName(live.GetName())
+	}
+	if manifest.GetName() != live.GetName() {
+		return nil, fmt.Errorf("manifest is for %s, not %s", manifest.GetName(), live.GetName())
+	}
+	if manifest.GetNamespace() == "" {
+		manifest.SetNamespace(live.GetNamespace())
+	}
+	if manifest.GetNamespace() != live.GetNamespace() {
+		return nil, fmt.Errorf("manifest is in namespace %s, not %s", manifest.GetNamespace(), live.GetNamespace())
+	}
+	if manifest.GetResourceVersion() == "" {
+		manifest.SetResourceVersion(live.GetResourceVersion())
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(manifest.Object, "metadata", "ownerReferences"); !found {
+		manifest.SetOwnerReferences(live.GetOwnerReferences())
+	}
+	if manifest.GetAPIVersion() == "" {
+		manifest.SetAPIVersion(live.GetAPIVersion())
+	}
+	if manifest.GetKind() == "" {
+		manifest.SetKind(live.GetKind())
+	}
+
+	return &manifest, nil
+}
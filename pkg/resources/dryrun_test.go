@@ -0,0 +1,82 @@
+package resources
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/docker/go-p9p"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDryRunDiff(t *testing.T) {
+	live := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":            "web",
+			"namespace":       "default",
+			"uid":             "web-uid",
+			"resourceVersion": "7",
+			"managedFields": []interface{}{
+				map[string]interface{}{"manager": "kubectl", "operation": "Update"},
+			},
+		},
+		"spec":   map[string]interface{}{"replicas": int64(1)},
+		"status": map[string]interface{}{"replicas": int64(1)},
+	}}
+
+	liveJSON, err := live.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The server returns the live object, and the submitted manifest as
+	// the result of the dry run.
+	session := newTestSession(t)
+	requests, closeServer := serveTestAPI(t, session, func(request apiRequest) string {
+		if request.method == "PUT" {
+			return request.body
+		}
+		return string(liveJSON)
+	})
+	defer closeServer()
+
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	ref, err := newDryRun(deployment, deploymentsResource, session).Open(context.Background(), p9p.ORDWR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dryrun := ref.(*DryRun)
+
+	manifest := "spec:\n  replicas: 3\n"
+	if _, err := dryrun.Write(context.Background(), []byte(manifest), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	content := readAll(t, dryrun, 0)
+	diff := content[:strings.Index(content, "\n\n")]
+	for _, line := range strings.Split(diff, "\n") {
+		if !strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "-") {
+			continue
+		}
+		switch line {
+		case "--- live", "+++ dryrun", "-  replicas: 1", "+  replicas: 3":
+		default:
+			t.Errorf("unexpected line %q in diff:\n%s", line, diff)
+		}
+	}
+	if !strings.Contains(diff, "+  replicas: 3") {
+		t.Errorf("diff doesn't contain the change:\n%s", diff)
+	}
+
+	for _, request := range requests() {
+		query, _ := url.ParseQuery(request.query)
+		if request.method == "PUT" && query.Get("dryRun") != "All" {
+			t.Errorf("got update with query %q, want a dry run", request.query)
+		}
+	}
+}
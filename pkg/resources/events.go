@@ -0,0 +1,210 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/go-p9p"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Events is a file listing the events in a namespace accepted by filter,
+// oldest first. Each line contains the time, type, reason, object and message
+// of an event, separated by tabs.
+//
+// The file grows as new events arrive. Each open fid remembers the newest
+// event it has returned, and reading past the end appends the events observed
+// since, so lines already read never change.
+type Events struct {
+	name      string
+	namespace string
+	object    metav1.Object
+	session   Session
+	filter    func(event *v1.Event) bool
+	info      *p9p.Dir
+
+	content []byte
+	last    time.Time
+	// sent holds the events returned that were observed at last, as
+	// several events may be observed within the same second.
+	sent map[string]bool
+}
+
+// newEvents returns an events file listing the events in namespace accepted by
+// filter.
+func newEvents(namespace string, session Session, filter func(event *v1.Event) bool) *Events {
+	return &Events{
+		name:      "events",
+		namespace: namespace,
+		session:   session,
+		filter:    filter,
+	}
+}
+
+func (r *Events) Info() p9p.Dir {
+	if r.info != nil {
+		return *r.info
+	}
+
+	dir := p9p.Dir{}
+	dir.Qid.Path = rand.Uint64()
+	dir.Qid.Version = 0
+
+	dir.Name = r.name
+	dir.Mode = modeReadOnly
+	dir.Length = 0
+	dir.AccessTime = time.Now()
+	dir.ModTime = time.Now()
+	setOwnership(&dir, r.object, r.session)
+
+	dir.Qid.Type |= p9p.QTFILE
+
+	r.info = &dir
+	return dir
+}
+
+func (r *Events) Get(name string) (Ref, error) {
+	return nil, p9p.ErrWalknodir
+}
+
+// Open returns an Events for the opened fid, which starts from the oldest
+// event.
+func (r *Events) Open(ctx context.Context, mode p9p.Flag) (Ref, error) {
+	return &Events{
+		name:      r.name,
+		namespace: r.namespace,
+		object:    r.object,
+		session:   r.session,
+		filter:    r.filter,
+		info:      r.info,
+	}, nil
+}
+
+func (r *Events) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	if r.content == nil || offset >= int64(len(r.content)) {
+		if err := r.appendNew(); err != nil {
+			return 0, err
+		}
+	}
+
+	return readAt(p, r.content, offset)
+}
+
+// list returns the events accepted by the filter, oldest first.
+func (r *Events) list() ([]*v1.Event, error) {
+	events, err := r.session.Informer().Core().V1().Events().Lister().Events(r.namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*v1.Event, 0, len(events))
+	for _, event := range events {
+		if r.filter == nil || r.filter(event) {
+			filtered = append(filtered, event)
+		}
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return eventTime(filtered[i]).Before(eventTime(filtered[j]))
+	})
+
+	return filtered, nil
+}
+
+// appendNew appends the lines of the events observed since the newest event
+// already returned. An event observed again, such as a repeated event with an
+// increased count, is appended as a new line.
+func (r *Events) appendNew() error {
+	events, err := r.list()
+	if err != nil {
+		return err
+	}
+
+	if r.content == nil {
+		r.content = []byte{}
+	}
+
+	buf := bytes.NewBuffer(r.content)
+	for _, event := range events {
+		t := eventTime(event)
+		key := string(event.UID) + "/" + event.ResourceVersion
+		if t.Before(r.last) || (t.Equal(r.last) && r.sent[key]) {
+			continue
+		}
+
+		if t.After(r.last) {
+			r.last = t
+			r.sent = make(map[string]bool)
+		}
+		r.sent[key] = true
+
+		fmt.Fprintf(buf, "%s\t%s\t%s\t%s/%s\t%s\n",
+			t.UTC().Format(time.RFC3339),
+			event.Type,
+			event.Reason,
+			strings.ToLower(event.InvolvedObject.Kind),
+			event.InvolvedObject.Name,
+			strings.TrimSpace(event.Message),
+		)
+	}
+
+	r.content = buf.Bytes()
+	return nil
+}
+
+// newObjectEvents returns an events file listing the events involving object.
+func newObjectEvents(object Object, session Session) *Events {
+	events := newEvents(object.GetNamespace(), session, involving(object))
+	events.object = object
+	return events
+}
+
+// involving returns an event filter accepting events involving object.
+func involving(object Object) func(event *v1.Event) bool {
+	return func(event *v1.Event) bool {
+		return event.InvolvedObject.UID == object.GetUID()
+	}
+}
+
+// selectEvents returns an events file for a name of the form
+// events%selector, listing the events accepted by filter that also match the
+// field selector, such as events%type=Warning.
+func selectEvents(name string, namespace string, session Session, filter func(event *v1.Event) bool) (Ref, error) {
+	query, err := url.PathUnescape(strings.TrimPrefix(name, "events%"))
+	if err != nil {
+		return nil, p9p.ErrNotfound
+	}
+	selector, err := fields.ParseSelector(query)
+	if err != nil {
+		return nil, p9p.ErrNotfound
+	}
+
+	events := newEvents(namespace, session, func(event *v1.Event) bool {
+		return (filter == nil || filter(event)) && matchesFields(event, selector)
+	})
+	events.name = name
+	return events, nil
+}
+
+// eventTime returns the time an event was last observed.
+func eventTime(event *v1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	}
+
+	return event.CreationTimestamp.Time
+}
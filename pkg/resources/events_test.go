@@ -0,0 +1,148 @@
+package resources
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/go-p9p"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testSession is a Session backed by a fake clientset.
+type testSession struct {
+	p9p.Session
+	client   kubernetes.Interface
+	dynamic  dynamic.Interface
+	informer informers.SharedInformerFactory
+	access   *Access
+}
+
+func newTestSession(t *testing.T, objects ...runtime.Object) *testSession {
+	client := fake.NewSimpleClientset(objects...)
+	informer := informers.NewSharedInformerFactory(client, 0)
+	informer.Core().V1().Events().Informer()
+	informer.Core().V1().Pods().Informer()
+	informer.Batch().V1().Jobs().Informer()
+
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	informer.Start(stopCh)
+	informer.WaitForCacheSync(stopCh)
+
+	return &testSession{client: client, informer: informer, access: NewAccess(client)}
+}
+
+func (s *testSession) GetAuth() (string, string)                 { return "tester", "/" }
+func (s *testSession) Client() kubernetes.Interface              { return s.client }
+func (s *testSession) Dynamic() dynamic.Interface                { return s.dynamic }
+func (s *testSession) Informer() informers.SharedInformerFactory { return s.informer }
+func (s *testSession) Access() *Access                           { return s.access }
+
+// readAll reads a file from offset until it returns no more data.
+func readAll(t *testing.T, ref Ref, offset int64) string {
+	t.Helper()
+
+	var b strings.Builder
+	p := make([]byte, 64)
+	for {
+		n, err := ref.Read(context.Background(), p, offset)
+		if err != nil {
+			t.Fatalf("read at %d: %v", offset, err)
+		}
+		if n == 0 {
+			return b.String()
+		}
+		b.Write(p[:n])
+		offset += int64(n)
+	}
+}
+
+func testEvent(name, resourceVersion, reason string, last time.Time) *v1.Event {
+	return &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			UID:             types.UID("uid-" + name),
+			ResourceVersion: resourceVersion,
+		},
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Name: "web"},
+		Type:           v1.EventTypeNormal,
+		Reason:         reason,
+		Message:        reason,
+		LastTimestamp:  metav1.NewTime(last),
+	}
+}
+
+// waitForEvents waits for the informer to observe n events.
+func waitForEvents(t *testing.T, session *testSession, n int) {
+	t.Helper()
+
+	lister := session.informer.Core().V1().Events().Lister()
+	for i := 0; i < 100; i++ {
+		events, _ := lister.List(labels.Everything())
+		if len(events) == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("informer did not observe %d events", n)
+}
+
+func TestEventsAppendOnly(t *testing.T) {
+	start := time.Date(2019, 11, 1, 12, 0, 0, 0, time.UTC)
+	session := newTestSession(t, testEvent("a", "1", "Scheduled", start))
+
+	ref, err := newEvents("default", session, nil).Open(context.Background(), p9p.OREAD)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := readAll(t, ref, 0)
+	if want := "2019-11-01T12:00:00Z\tNormal\tScheduled\tpod/web\tScheduled\n"; first != want {
+		t.Fatalf("got %q, want %q", first, want)
+	}
+
+	// A new event, and a repeat of the first event observed later.
+	events := session.client.CoreV1().Events("default")
+	if _, err := events.Create(testEvent("b", "2", "Pulled", start.Add(time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := events.Update(testEvent("a", "3", "Scheduled", start.Add(2*time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	waitForEvents(t, session, 2)
+	for i := 0; i < 100 && readAll(t, ref, 0) == first; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	all := readAll(t, ref, 0)
+	if !strings.HasPrefix(all, first) {
+		t.Fatalf("content read before changed: got %q", all)
+	}
+
+	want := first +
+		"2019-11-01T12:01:00Z\tNormal\tPulled\tpod/web\tPulled\n" +
+		"2019-11-01T12:02:00Z\tNormal\tScheduled\tpod/web\tScheduled\n"
+	for i := 0; i < 100 && all != want; i++ {
+		time.Sleep(10 * time.Millisecond)
+		all = readAll(t, ref, 0)
+	}
+	if all != want {
+		t.Fatalf("got %q, want %q", all, want)
+	}
+
+	// Reading past the end again doesn't repeat events already returned.
+	if rest := readAll(t, ref, int64(len(all))); rest != "" {
+		t.Fatalf("got %q after the end, want nothing", rest)
+	}
+}
@@ -0,0 +1,32 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// matchesFields reports if object matches the field selector. Fields are
+// dotted paths into the object, such as status.phase, and are evaluated
+// against the cached object rather than by the API server, so any field can
+// be selected on.
+func matchesFields(object runtime.Object, selector fields.Selector) bool {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return false
+	}
+
+	set := fields.Set{}
+	for _, requirement := range selector.Requirements() {
+		value, found, err := unstructured.NestedFieldNoCopy(content, strings.Split(requirement.Field, ".")...)
+		if err != nil || !found {
+			continue
+		}
+		set[requirement.Field] = fmt.Sprint(value)
+	}
+
+	return selector.Matches(set)
+}
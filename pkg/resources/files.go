@@ -1,19 +1,23 @@
 package resources
 
 import (
+	"bytes"
 	"context"
 	"math/rand"
 	"time"
 
 	"github.com/docker/go-p9p"
 	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// Static is a file with fixed content. The file is shared by every fid walked
+// to it, so reads may be at any offset.
 type Static struct {
 	name    string
-	offset  int64
 	content []byte
 	info    *p9p.Dir
+	object  metav1.Object
 	session Session
 }
 
@@ -27,15 +31,11 @@ func (r *Static) Info() p9p.Dir {
 	dir.Qid.Version = 0
 
 	dir.Name = r.name
-	dir.Mode = 0664
+	dir.Mode = modeReadOnly
 	dir.Length = 0
-	dir.AccessTime = time.Now()
-	dir.ModTime = time.Now()
-	dir.MUID = "none"
-
-	uname, _ := r.session.GetAuth()
-	dir.UID = uname
-	dir.GID = uname
+	dir.ModTime = lastModified(r.object)
+	dir.AccessTime = dir.ModTime
+	setOwnership(&dir, r.object, r.session)
 
 	dir.Qid.Type |= p9p.QTFILE
 
@@ -48,13 +48,177 @@ func (r *Static) Get(name string) (Ref, error) {
 }
 
 func (r *Static) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
-	log.Debug().Int64("offset", r.offset).Str("name", r.name).Send()
-	if offset != r.offset {
+	return readAt(p, r.content, offset)
+}
+
+// readAt reads content from offset into p. Offsets from clients are
+// unsigned, so offsets beyond the largest int64 arrive negative and are
+// rejected.
+func readAt(p []byte, content []byte, offset int64) (int, error) {
+	if offset < 0 {
 		return 0, p9p.ErrBadoffset
 	}
+	if offset >= int64(len(content)) {
+		return 0, nil
+	}
+
+	return copy(p, content[offset:]), nil
+}
+
+// Ctl is a file that hands a command to a function, in the style of Plan 9
+// control files. Reads return the content the file was created with.
+//
+// Writes are buffered for each open fid, and the command is run when the fid
+// is clunked, so a command split across several writes runs once.
+type Ctl struct {
+	name    string
+	content []byte
+	info    *p9p.Dir
+	object  metav1.Object
+	session Session
+	write   func(ctx context.Context, p []byte) error
+	command []byte
+	written bool
+}
+
+func (r *Ctl) Info() p9p.Dir {
+	if r.info != nil {
+		return *r.info
+	}
+
+	dir := p9p.Dir{}
+	dir.Qid.Path = rand.Uint64()
+	dir.Qid.Version = 0
+
+	dir.Name = r.name
+	dir.Mode = modeWriteOnly
+	if len(r.content) > 0 {
+		dir.Mode = modeReadWrite
+	}
+	dir.Length = 0
+	dir.ModTime = lastModified(r.object)
+	dir.AccessTime = dir.ModTime
+	setOwnership(&dir, r.object, r.session)
+
+	dir.Qid.Type |= p9p.QTFILE
+
+	r.info = &dir
+	return dir
+}
+
+func (r *Ctl) Get(name string) (Ref, error) {
+	return nil, p9p.ErrWalknodir
+}
 
-	n = copy(p, r.content[offset:])
-	r.offset += int64(n)
+func (r *Ctl) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	return readAt(p, r.content, offset)
+}
+
+// Open returns a Ctl for the opened fid, with its own command buffer.
+func (r *Ctl) Open(ctx context.Context, mode p9p.Flag) (Ref, error) {
+	return &Ctl{
+		name:    r.name,
+		content: r.content,
+		info:    r.info,
+		object:  r.object,
+		session: r.session,
+		write:   r.write,
+	}, nil
+}
+
+func (r *Ctl) Write(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	if offset < 0 || offset != int64(len(r.command)) {
+		return 0, p9p.ErrBadoffset
+	}
+
+	r.command = append(r.command, p...)
+	r.written = true
+
+	return len(p), nil
+}
+
+// Close runs the command written to the fid, if any.
+func (r *Ctl) Close(ctx context.Context) error {
+	if !r.written {
+		return nil
+	}
+
+	if err := r.write(ctx, bytes.TrimSpace(r.command)); err != nil {
+		// Clients often ignore errors when closing files, so make sure
+		// failed commands are noticed.
+		log.Warn().Err(err).Str("name", r.name).Msg("error running ctl command")
+		return err
+	}
+
+	return nil
+}
+
+// Dynamic is a file whose content is generated when it is read. Each open fid
+// reads a single snapshot, which is regenerated only when reading from the
+// start of the file again.
+//
+// Files generated only from object set objectTime, so their modification time
+// is when the object last changed.
+type Dynamic struct {
+	name       string
+	content    []byte
+	info       *p9p.Dir
+	object     metav1.Object
+	objectTime bool
+	session    Session
+	generate   func(ctx context.Context) ([]byte, error)
+}
+
+func (r *Dynamic) Info() p9p.Dir {
+	if r.info != nil {
+		return *r.info
+	}
+
+	dir := p9p.Dir{}
+	dir.Qid.Path = rand.Uint64()
+	dir.Qid.Version = 0
+
+	dir.Name = r.name
+	dir.Mode = modeReadOnly
+	dir.Length = 0
+	dir.AccessTime = time.Now()
+	dir.ModTime = time.Now()
+	if r.objectTime {
+		dir.ModTime = lastModified(r.object)
+		dir.AccessTime = dir.ModTime
+	}
+	setOwnership(&dir, r.object, r.session)
+
+	dir.Qid.Type |= p9p.QTFILE
+
+	r.info = &dir
+	return dir
+}
+
+func (r *Dynamic) Get(name string) (Ref, error) {
+	return nil, p9p.ErrWalknodir
+}
+
+// Open returns a Dynamic for the opened fid, with its own snapshot.
+func (r *Dynamic) Open(ctx context.Context, mode p9p.Flag) (Ref, error) {
+	return &Dynamic{
+		name:       r.name,
+		info:       r.info,
+		object:     r.object,
+		objectTime: r.objectTime,
+		session:    r.session,
+		generate:   r.generate,
+	}, nil
+}
+
+func (r *Dynamic) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	if offset == 0 || r.content == nil {
+		content, err := r.generate(ctx)
+		if err != nil {
+			return 0, err
+		}
+		r.content = content
+	}
 
-	return n, nil
+	return readAt(p, r.content, offset)
 }
@@ -0,0 +1,64 @@
+package resources
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/docker/go-p9p"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStaticReadTwice(t *testing.T) {
+	ref := &Static{name: "rollout", content: []byte("deployment \"web\" successfully rolled out\n")}
+
+	// Every fid walked to the file shares it, so each reads from the start.
+	for i := 0; i < 2; i++ {
+		if got, want := readAll(t, ref, 0), string(ref.content); got != want {
+			t.Fatalf("read %d: got %q, want %q", i, got, want)
+		}
+	}
+
+	if got := readAll(t, ref, 11); got != "\"web\" successfully rolled out\n" {
+		t.Fatalf("got %q reading from the middle", got)
+	}
+}
+
+func TestNegativeOffset(t *testing.T) {
+	session := newTestSession(t)
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	content := []byte("content\n")
+	generate := func(ctx context.Context) ([]byte, error) { return content, nil }
+
+	// Offsets are unsigned on the wire, so those past the largest int64
+	// arrive negative.
+	const offset = math.MinInt64
+
+	readers := map[string]Ref{
+		"static":  &Static{name: "rollout", content: content},
+		"ctl":     &Ctl{name: "scale", content: content},
+		"dynamic": &Dynamic{name: "describe", generate: generate},
+		"events":  newEvents("default", session, nil),
+		"spec":    &Spec{content: content},
+		"dryrun":  &DryRun{content: content},
+		"value":   &MetadataValueRef{key: "app", content: content},
+	}
+	for name, ref := range readers {
+		if _, err := ref.Read(context.Background(), make([]byte, 64), offset); err != p9p.ErrBadoffset {
+			t.Errorf("%s: got error %v reading, want %v", name, err, p9p.ErrBadoffset)
+		}
+	}
+
+	writers := map[string]Writer{
+		"ctl":    &Ctl{name: "scale"},
+		"spec":   &Spec{content: content, dirty: true},
+		"dryrun": newDryRun(deployment, deploymentsResource, session),
+		"value":  &MetadataValueRef{key: "app", content: content},
+	}
+	for name, ref := range writers {
+		if _, err := ref.Write(context.Background(), []byte("x"), offset); err != p9p.ErrBadoffset {
+			t.Errorf("%s: got error %v writing, want %v", name, err, p9p.ErrBadoffset)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package resources
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var jobsResource = v1.SchemeGroupVersion.WithResource("jobs")
+
+func NewJobs(namespace string, session Session) *Collection {
+	lister := session.Informer().Batch().V1().Jobs().Lister().Jobs(namespace)
+	return &Collection{
+		name:      "jobs",
+		namespace: namespace,
+		resource:  jobsResource,
+		session:   session,
+		list: func(selector labels.Selector) ([]Object, error) {
+			jobs, err := lister.List(selector)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]Object, 0, len(jobs))
+			for _, job := range jobs {
+				objects = append(objects, job)
+			}
+			return objects, nil
+		},
+		get: func(name string) (Object, error) {
+			return lister.Get(name)
+		},
+		newRef: func(object Object) Ref {
+			return NewJobRef(object.(*v1.Job), session)
+		},
+		columns: jobColumns,
+	}
+}
+
+// jobColumns are the _table columns of jobs, following kubectl get jobs.
+var jobColumns = []column{
+	{"COMPLETIONS", func(object Object) string {
+		job := object.(*v1.Job)
+		completions := "<none>"
+		if job.Spec.Completions != nil {
+			completions = fmt.Sprint(*job.Spec.Completions)
+		}
+		return fmt.Sprintf("%d/%s", job.Status.Succeeded, completions)
+	}},
+	{"DURATION", func(object Object) string {
+		return strings.TrimSpace(jobDuration(object.(*v1.Job)))
+	}},
+}
+
+// newOwnedJobs returns a collection of the jobs with an owner reference to
+// owner.
+func newOwnedJobs(owner Object, session Session) *Collection {
+	return NewJobs(owner.GetNamespace(), session).filter(func(object Object) bool {
+		return ownedBy(object, owner)
+	})
+}
+
+func NewJobRef(job *v1.Job, session Session) *ObjectRef {
+	completions := "<none>"
+	if job.Spec.Completions != nil {
+		completions = fmt.Sprint(*job.Spec.Completions)
+	}
+
+	return NewObjectRef(job, jobsResource, session, map[string]Ref{
+		"completions": &Static{
+			name:    "completions",
+			content: []byte(fmt.Sprintf("%d/%s\n", job.Status.Succeeded, completions)),
+			object:  job,
+			session: session,
+		},
+		"failures": &Static{
+			name:    "failures",
+			content: []byte(fmt.Sprintf("%d\n", job.Status.Failed)),
+			object:  job,
+			session: session,
+		},
+		"duration": &Static{
+			name:    "duration",
+			content: []byte(jobDuration(job)),
+			object:  job,
+			session: session,
+		},
+		"log": &Dynamic{
+			name:    "log",
+			object:  job,
+			session: session,
+			generate: func(ctx context.Context) ([]byte, error) {
+				return jobLogs(ctx, job, session)
+			},
+		},
+		"pods": newOwnedPods(job, session),
+	})
+}
+
+// jobDuration returns how long the job ran for, or has been running for if it
+// has not yet completed.
+func jobDuration(job *v1.Job) string {
+	if job.Status.StartTime == nil {
+		return ""
+	}
+
+	end := time.Now()
+	if job.Status.CompletionTime != nil {
+		end = job.Status.CompletionTime.Time
+	}
+
+	return end.Sub(job.Status.StartTime.Time).Round(time.Second).String() + "\n"
+}
+
+// jobLogs combines the logs of every container of the pods owned by job. Each
+// line is prefixed with the pod and container it came from. The log streams
+// are bound to ctx, so flushing the read closes them.
+func jobLogs(ctx context.Context, job *v1.Job, session Session) ([]byte, error) {
+	pods, err := newOwnedPods(job, session).list(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pods, func(i, j int) bool {
+		ti, tj := pods[i].GetCreationTimestamp(), pods[j].GetCreationTimestamp()
+		return ti.Before(&tj)
+	})
+
+	var buf bytes.Buffer
+	for _, object := range pods {
+		pod := object.(*corev1.Pod)
+		for _, container := range pod.Spec.Containers {
+			req := session.Client().CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+				Container: container.Name,
+			})
+
+			stream, err := req.Context(ctx).Stream()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				fmt.Fprintf(&buf, "[%s/%s] %v\n", pod.Name, container.Name, err)
+				continue
+			}
+
+			err = prefixLines(&buf, stream, fmt.Sprintf("[%s/%s] ", pod.Name, container.Name))
+			stream.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// prefixLines copies the lines of r to w with prefix before each. Lines may be
+// of any length, as containers often log large JSON documents on one line.
+func prefixLines(w io.Writer, r io.Reader, prefix string) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			fmt.Fprintf(w, "%s%s\n", prefix, strings.TrimSuffix(line, "\n"))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestJobLogsCancel(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default", UID: "job-uid"},
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "backup-1",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: "backup", UID: "job-uid"}},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "main"}}},
+	}
+	session := newTestSession(t, job, pod)
+
+	// The log stream writes a line, then follows until the client goes away.
+	streaming := make(chan struct{})
+	closed := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/default/pods/backup-1/log" {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprintln(w, "starting backup")
+		w.(http.Flusher).Flush()
+		close(streaming)
+
+		<-r.Context().Done()
+		close(closed)
+	}))
+	defer server.Close()
+
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.client = client
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := jobLogs(ctx, job, session)
+		done <- err
+	}()
+
+	select {
+	case <-streaming:
+	case <-time.After(5 * time.Second):
+		t.Fatal("log stream was not requested")
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("got error %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reading the logs did not return after cancelling")
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("log stream was not closed after cancelling")
+	}
+}
+
+func TestPrefixLines(t *testing.T) {
+	// Lines longer than the 64 KiB limit of bufio.Scanner, and a last line
+	// without a newline, are copied whole.
+	long := strings.Repeat("x", 100*1024)
+	input := "starting\n" + long + "\ndone"
+
+	var buf strings.Builder
+	if err := prefixLines(&buf, strings.NewReader(input), "[pod/main] "); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[pod/main] starting\n[pod/main] " + long + "\n[pod/main] done\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %d bytes, want %d bytes of prefixed lines", len(got), len(want))
+	}
+}
@@ -0,0 +1,242 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/url"
+
+	"github.com/docker/go-p9p"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// MetadataRef is a directory of the labels or annotations of an object. Each
+// key is a file containing its value. A value written to a file is set when
+// the file is clunked, creating a file adds a key, and removing a file deletes
+// the key, all with merge patches against the object.
+//
+// Keys may contain slashes, such as app.kubernetes.io/name, so file names are
+// the URL path escaped key.
+type MetadataRef struct {
+	name     string
+	values   map[string]string
+	object   Object
+	resource schema.GroupVersionResource
+	session  Session
+	info     *p9p.Dir
+	readdir  *p9p.Readdir
+}
+
+func newLabels(object Object, resource schema.GroupVersionResource, session Session) *MetadataRef {
+	return &MetadataRef{
+		name:     "labels",
+		values:   object.GetLabels(),
+		object:   object,
+		resource: resource,
+		session:  session,
+	}
+}
+
+func newAnnotations(object Object, resource schema.GroupVersionResource, session Session) *MetadataRef {
+	return &MetadataRef{
+		name:     "annotations",
+		values:   object.GetAnnotations(),
+		object:   object,
+		resource: resource,
+		session:  session,
+	}
+}
+
+// patch sets key to value with a merge patch. A nil value deletes the key.
+func (r *MetadataRef) patch(ctx context.Context, key string, value *string) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			r.name: map[string]*string{
+				key: value,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return patchObject(ctx, r.session, r.resource, r.object.GetNamespace(), r.object.GetName(), data)
+}
+
+// writable reports if the user may patch the object.
+func (r *MetadataRef) writable() bool {
+	return r.session.Access().Allowed("patch", r.resource, r.object.GetNamespace(), r.object.GetName())
+}
+
+func (r *MetadataRef) Info() p9p.Dir {
+	if r.info != nil {
+		return *r.info
+	}
+
+	dir := p9p.Dir{}
+	dir.Qid.Path = rand.Uint64()
+	dir.Qid.Version = 0
+
+	dir.Name = r.name
+	dir.Mode = modeDir
+	if r.writable() {
+		dir.Mode |= modeWritable
+	}
+	dir.Length = 0
+	dir.ModTime = lastModified(r.object)
+	dir.AccessTime = dir.ModTime
+	setOwnership(&dir, r.object, r.session)
+
+	dir.Qid.Type |= p9p.QTDIR
+	dir.Mode |= p9p.DMDIR
+	r.info = &dir
+
+	return dir
+}
+
+func (r *MetadataRef) Get(name string) (Ref, error) {
+	key, err := url.PathUnescape(name)
+	if err != nil {
+		return nil, p9p.ErrNotfound
+	}
+
+	value, ok := r.values[key]
+	if !ok {
+		return nil, p9p.ErrNotfound
+	}
+
+	return r.newValue(key, value), nil
+}
+
+func (r *MetadataRef) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	if r.readdir != nil {
+		return r.readdir.Read(ctx, p, offset)
+	}
+
+	dir := make([]p9p.Dir, 0, len(r.values))
+	for key, value := range r.values {
+		dir = append(dir, r.newValue(key, value).Info())
+	}
+
+	r.readdir = p9p.NewFixedReaddir(p9p.NewCodec(), dir)
+	return r.readdir.Read(ctx, p, offset)
+}
+
+func (r *MetadataRef) Create(ctx context.Context, name string, perm uint32, mode p9p.Flag) (Ref, error) {
+	if perm&p9p.DMDIR != 0 {
+		return nil, p9p.ErrNocreate
+	}
+
+	key, err := url.PathUnescape(name)
+	if err != nil {
+		return nil, err
+	}
+
+	value := ""
+	if err := r.patch(ctx, key, &value); err != nil {
+		return nil, err
+	}
+
+	return r.newValue(key, value), nil
+}
+
+func (r *MetadataRef) newValue(key string, value string) *MetadataValueRef {
+	return &MetadataValueRef{
+		key:      key,
+		content:  []byte(value),
+		metadata: r,
+	}
+}
+
+// MetadataValueRef is a file containing the value of a label or annotation.
+//
+// Writes are buffered for each open fid, and the value is set when the fid is
+// clunked, so a value split across several writes is patched once.
+type MetadataValueRef struct {
+	key      string
+	content  []byte
+	metadata *MetadataRef
+	info     *p9p.Dir
+	written  bool
+}
+
+func (r *MetadataValueRef) Info() p9p.Dir {
+	if r.info != nil {
+		return *r.info
+	}
+
+	dir := p9p.Dir{}
+	dir.Qid.Path = rand.Uint64()
+	dir.Qid.Version = 0
+
+	dir.Name = url.PathEscape(r.key)
+	dir.Mode = modeReadOnly
+	if r.metadata.writable() {
+		dir.Mode = modeReadWrite
+	}
+	dir.Length = uint64(len(r.content))
+	dir.ModTime = lastModified(r.metadata.object)
+	dir.AccessTime = dir.ModTime
+	setOwnership(&dir, r.metadata.object, r.metadata.session)
+
+	dir.Qid.Type |= p9p.QTFILE
+
+	r.info = &dir
+	return dir
+}
+
+func (r *MetadataValueRef) Get(name string) (Ref, error) {
+	return nil, p9p.ErrWalknodir
+}
+
+func (r *MetadataValueRef) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	return readAt(p, r.content, offset)
+}
+
+// Open returns a MetadataValueRef for the opened fid, with its own copy of
+// the value.
+func (r *MetadataValueRef) Open(ctx context.Context, mode p9p.Flag) (Ref, error) {
+	opened := r.metadata.newValue(r.key, string(r.content))
+	if mode&p9p.OTRUNC != 0 {
+		opened.content = nil
+		opened.written = true
+	}
+
+	return opened, nil
+}
+
+// Write sets the value of the key. Writes past the start of the file extend
+// the value, so values larger than a single message can be written.
+func (r *MetadataValueRef) Write(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	if offset < 0 || offset > int64(len(r.content)) {
+		return 0, p9p.ErrBadoffset
+	}
+
+	r.content = append(r.content[:offset:offset], p...)
+	r.written = true
+	r.info = nil
+	return len(p), nil
+}
+
+// Close sets the value written to the fid, if any.
+func (r *MetadataValueRef) Close(ctx context.Context) error {
+	if !r.written {
+		return nil
+	}
+
+	value := string(bytes.TrimRight(r.content, "\n"))
+	if err := r.metadata.patch(ctx, r.key, &value); err != nil {
+		// Clients often ignore errors when closing files, so make sure
+		// failed patches are noticed.
+		log.Warn().Err(err).Str("key", r.key).Msg("error setting " + r.metadata.name)
+		return err
+	}
+
+	return nil
+}
+
+func (r *MetadataValueRef) Remove(ctx context.Context) error {
+	return r.metadata.patch(ctx, r.key, nil)
+}
@@ -0,0 +1,157 @@
+package resources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/go-p9p"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testDeploymentJSON = `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"default"}}`
+
+func testDeployment() *appsv1.Deployment {
+	return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:      "web",
+		Namespace: "default",
+		Labels: map[string]string{
+			"app.kubernetes.io/name": "web",
+			"tier":                   "frontend",
+		},
+	}}
+}
+
+func TestMetadataKeys(t *testing.T) {
+	session := newTestSession(t)
+	labels := newLabels(testDeployment(), deploymentsResource, session)
+
+	// Keys with slashes are named by their escaped key, and walking to the
+	// name finds the key.
+	names := map[string]string{
+		"app.kubernetes.io%2Fname": "web",
+		"tier":                     "frontend",
+	}
+	for name, value := range names {
+		ref, err := labels.Get(name)
+		if err != nil {
+			t.Errorf("get %s: %v", name, err)
+			continue
+		}
+		if got := ref.Info().Name; got != name {
+			t.Errorf("got name %q, want %q", got, name)
+		}
+		if got := readAll(t, ref, 0); got != value {
+			t.Errorf("got value %q for %s, want %q", got, name, value)
+		}
+	}
+
+	if _, err := labels.Get("app.kubernetes.io%2Fmissing"); err != p9p.ErrNotfound {
+		t.Errorf("got error %v for a missing key, want %v", err, p9p.ErrNotfound)
+	}
+}
+
+func TestMetadataPatches(t *testing.T) {
+	session := newTestSession(t)
+	requests, closeServer := serveTestAPI(t, session, respondWith(testDeploymentJSON))
+	defer closeServer()
+
+	labels := newLabels(testDeployment(), deploymentsResource, session)
+	ctx := context.Background()
+
+	// Creating a file adds the key with an empty value.
+	created, err := labels.Create(ctx, "app.kubernetes.io%2Fpart-of", 0644, p9p.OWRITE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A value written in several messages is set once, on clunk.
+	value := created.(*MetadataValueRef)
+	if _, err := value.Write(ctx, []byte("shop"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := value.Write(ctx, []byte("front\n"), 4); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(requests()); got != 1 {
+		t.Fatalf("got %d requests before clunking, want 1", got)
+	}
+	if err := value.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Removing a file deletes the key.
+	ref, err := labels.Get("app.kubernetes.io%2Fname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ref.(Remover).Remove(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`{"metadata":{"labels":{"app.kubernetes.io/part-of":""}}}`,
+		`{"metadata":{"labels":{"app.kubernetes.io/part-of":"shopfront"}}}`,
+		`{"metadata":{"labels":{"app.kubernetes.io/name":null}}}`,
+	}
+	got := requests()
+	if len(got) != len(want) {
+		t.Fatalf("got %d requests, want %d", len(got), len(want))
+	}
+	for i, request := range got {
+		if request.method != "PATCH" || request.path != "/apis/apps/v1/namespaces/default/deployments/web" {
+			t.Errorf("got %s %s, want a patch of the deployment", request.method, request.path)
+		}
+		if request.contentType != "application/merge-patch+json" {
+			t.Errorf("got content type %q, want a merge patch", request.contentType)
+		}
+		if request.body != want[i] {
+			t.Errorf("got patch %s, want %s", request.body, want[i])
+		}
+	}
+}
+
+func TestMetadataModes(t *testing.T) {
+	tests := []struct {
+		verbs     []string
+		valueMode uint32
+		dirMode   uint32
+	}{
+		{verbs: []string{"get"}, valueMode: modeReadOnly, dirMode: modeDir},
+		{verbs: []string{"get", "patch"}, valueMode: modeReadWrite, dirMode: modeDir | modeWritable},
+	}
+
+	for _, test := range tests {
+		client := fake.NewSimpleClientset()
+		client.PrependReactor("create", "selfsubjectrulesreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, &authorizationv1.SelfSubjectRulesReview{
+				Status: authorizationv1.SubjectRulesReviewStatus{
+					ResourceRules: []authorizationv1.ResourceRule{{
+						Verbs:     test.verbs,
+						APIGroups: []string{"apps"},
+						Resources: []string{"deployments"},
+					}},
+				},
+			}, nil
+		})
+
+		session := newTestSession(t)
+		session.access = NewAccess(client)
+		labels := newLabels(testDeployment(), deploymentsResource, session)
+
+		if got := labels.Info().Mode &^ p9p.DMDIR; got != test.dirMode {
+			t.Errorf("%v: got directory mode %o, want %o", test.verbs, got, test.dirMode)
+		}
+		ref, err := labels.Get("tier")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ref.Info().Mode; got != test.valueMode {
+			t.Errorf("%v: got value mode %o, want %o", test.verbs, got, test.valueMode)
+		}
+	}
+}
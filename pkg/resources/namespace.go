@@ -3,6 +3,7 @@ package resources
 import (
 	"context"
 	"math/rand"
+	"strings"
 
 	"github.com/docker/go-p9p"
 	v1 "k8s.io/api/core/v1"
@@ -27,15 +28,11 @@ func (r *NamespaceRef) Info() p9p.Dir {
 	dir.Qid.Version = uint32(r.namespace.Generation)
 
 	dir.Name = r.namespace.Name
-	dir.Mode = 0664
+	dir.Mode = modeDir
 	dir.Length = 0
-	dir.AccessTime = r.namespace.CreationTimestamp.Time
-	dir.ModTime = r.namespace.CreationTimestamp.Time
-	dir.MUID = "none"
-
-	uname, _ := r.session.GetAuth()
-	dir.UID = uname
-	dir.GID = uname
+	dir.ModTime = lastModified(r.namespace)
+	dir.AccessTime = dir.ModTime
+	setOwnership(&dir, r.namespace, r.session)
 
 	dir.Qid.Type |= p9p.QTDIR
 	dir.Mode |= p9p.DMDIR
@@ -44,13 +41,30 @@ func (r *NamespaceRef) Info() p9p.Dir {
 	return dir
 }
 
+func (r *NamespaceRef) children() map[string]Ref {
+	return map[string]Ref{
+		"deployments":  NewDeployments(r.namespace.Name, r.session),
+		"statefulsets": NewStatefulSets(r.namespace.Name, r.session),
+		"daemonsets":   NewDaemonSets(r.namespace.Name, r.session),
+		"replicasets":  NewReplicaSets(r.namespace.Name, r.session),
+		"jobs":         NewJobs(r.namespace.Name, r.session),
+		"cronjobs":     NewCronJobs(r.namespace.Name, r.session),
+		"pods":         NewPods(r.namespace.Name, r.session),
+		"events":       newEvents(r.namespace.Name, r.session, nil),
+	}
+}
+
 func (r *NamespaceRef) Get(name string) (Ref, error) {
-	switch name {
-	case "deployments":
-		return NewDeployments(r.namespace.Name, r.session), nil
+	if strings.HasPrefix(name, "events%") {
+		return selectEvents(name, r.namespace.Name, r.session, nil)
+	}
+
+	child, ok := r.children()[name]
+	if !ok {
+		return nil, p9p.ErrNotfound
 	}
 
-	return nil, p9p.ErrNotfound
+	return child, nil
 }
 
 func (r *NamespaceRef) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
@@ -58,9 +72,10 @@ func (r *NamespaceRef) Read(ctx context.Context, p []byte, offset int64) (n int,
 		return r.readdir.Read(ctx, p, offset)
 	}
 
-	deployments := NewDeployments(r.namespace.Name, r.session)
-	dir := []p9p.Dir{
-		deployments.Info(),
+	children := r.children()
+	dir := make([]p9p.Dir, 0, len(children))
+	for _, child := range children {
+		dir = append(dir, child.Info())
 	}
 
 	r.readdir = p9p.NewFixedReaddir(p9p.NewCodec(), dir)
@@ -7,12 +7,15 @@ import (
 	"time"
 
 	"github.com/docker/go-p9p"
+	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	corev1 "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
+var namespacesResource = v1.SchemeGroupVersion.WithResource("namespaces")
+
 type NamespacesRef struct {
 	client            kubernetes.Interface
 	namespaceInformer corev1.NamespaceInformer
@@ -41,15 +44,11 @@ func (r *NamespacesRef) Info() p9p.Dir {
 	dir.Qid.Version = 0
 
 	dir.Name = "namespaces"
-	dir.Mode = 0664
+	dir.Mode = modeDir
 	dir.Length = 0
 	dir.AccessTime = time.Now()
 	dir.ModTime = time.Now()
-	dir.MUID = "none"
-
-	uname, _ := r.session.GetAuth()
-	dir.UID = uname
-	dir.GID = uname
+	setOwnership(&dir, nil, r.session)
 
 	dir.Qid.Type |= p9p.QTDIR
 	dir.Mode |= p9p.DMDIR
@@ -67,6 +66,10 @@ func (r *NamespacesRef) Get(name string) (Ref, error) {
 		return nil, err
 	}
 
+	if !r.session.Access().Allowed("get", namespacesResource, "", name) {
+		return nil, p9p.ErrNotfound
+	}
+
 	return &NamespaceRef{
 		namespace: namespace,
 		client:    r.client,
@@ -84,10 +87,16 @@ func (r *NamespacesRef) Read(ctx context.Context, p []byte, offset int64) (n int
 		return 0, err
 	}
 
+	access := r.session.Access()
+	listable := access.Allowed("list", namespacesResource, "", "")
+
 	namespaceRefs := make([]NamespaceRef, 0, len(namespaces))
 
 	for _, namespace := range namespaces {
 		namespace := namespace
+		if !listable && !access.AllowedListing("get", namespacesResource, "", namespace.Name) {
+			continue
+		}
 		namespaceRefs = append(namespaceRefs, NamespaceRef{
 			namespace: namespace,
 			client:    r.client,
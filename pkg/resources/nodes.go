@@ -0,0 +1,60 @@
+package resources
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var nodesResource = v1.SchemeGroupVersion.WithResource("nodes")
+
+func NewNodes(session Session) *Collection {
+	lister := session.Informer().Core().V1().Nodes().Lister()
+	return &Collection{
+		name:     "nodes",
+		resource: nodesResource,
+		session:  session,
+		list: func(selector labels.Selector) ([]Object, error) {
+			nodes, err := lister.List(selector)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]Object, 0, len(nodes))
+			for _, node := range nodes {
+				objects = append(objects, node)
+			}
+			return objects, nil
+		},
+		get: func(name string) (Object, error) {
+			return lister.Get(name)
+		},
+		newRef: func(object Object) Ref {
+			return NewNodeRef(object.(*v1.Node), session)
+		},
+		columns: nodeColumns,
+	}
+}
+
+// nodeColumns are the _table columns of nodes, following kubectl get nodes.
+var nodeColumns = []column{
+	{"STATUS", func(object Object) string {
+		node := object.(*v1.Node)
+		status := "NotReady"
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == v1.NodeReady && condition.Status == v1.ConditionTrue {
+				status = "Ready"
+			}
+		}
+		if node.Spec.Unschedulable {
+			status += ",SchedulingDisabled"
+		}
+		return status
+	}},
+	{"VERSION", func(object Object) string {
+		return object.(*v1.Node).Status.NodeInfo.KubeletVersion
+	}},
+}
+
+func NewNodeRef(node *v1.Node, session Session) *ObjectRef {
+	return NewObjectRef(node, nodesResource, session, map[string]Ref{})
+}
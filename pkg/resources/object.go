@@ -0,0 +1,295 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/docker/go-p9p"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// Object is a Kubernetes object that can be exposed as a directory.
+type Object interface {
+	metav1.Object
+	runtime.Object
+}
+
+// ObjectRef is the directory of a single Kubernetes object. Every object
+// directory contains the object as data.yaml and data.json, a writable
+// spec.yaml without status and server managed metadata, describe and
+// table files formatted like kubectl describe and kubectl get -o wide, events
+// and dryrun files, labels and annotations directories, and owners and owned
+// directories of related objects, along with children specific to the
+// resource type.
+type ObjectRef struct {
+	object   Object
+	resource schema.GroupVersionResource
+	session  Session
+	info     *p9p.Dir
+	readdir  *p9p.Readdir
+	children map[string]Ref
+}
+
+func NewObjectRef(object Object, resource schema.GroupVersionResource, session Session, children map[string]Ref) *ObjectRef {
+	all := map[string]Ref{
+		"data.yaml": newData("data.yaml", object, session, func(object runtime.Object) ([]byte, error) {
+			return yaml.Marshal(object)
+		}),
+		"data.json": newData("data.json", object, session, func(object runtime.Object) ([]byte, error) {
+			j, err := json.MarshalIndent(object, "", "  ")
+			return append(j, '\n'), err
+		}),
+		"spec.yaml":   newSpec(object, resource, session),
+		"describe":    newDescribe(object, session),
+		"table":       newTable(object, resource, session),
+		"events":      newObjectEvents(object, session),
+		"labels":      newLabels(object, resource, session),
+		"annotations": newAnnotations(object, resource, session),
+		"owners":      newOwners(object, session),
+		"owned":       newOwned(object, session),
+		"dryrun":      newDryRun(object, resource, session),
+	}
+	for name, child := range children {
+		all[name] = child
+	}
+
+	return &ObjectRef{
+		object:   object,
+		resource: resource,
+		session:  session,
+		children: all,
+	}
+}
+
+func (r *ObjectRef) Info() p9p.Dir {
+	if r.info != nil {
+		return *r.info
+	}
+
+	dir := p9p.Dir{}
+	dir.Qid.Path = rand.Uint64()
+	dir.Qid.Version = uint32(r.object.GetGeneration())
+
+	dir.Name = r.object.GetName()
+	dir.Mode = modeDir
+	if r.session.Access().AllowedListing("update", r.resource, r.object.GetNamespace(), r.object.GetName()) {
+		dir.Mode |= modeWritable
+	}
+	dir.Length = 0
+	dir.ModTime = lastModified(r.object)
+	dir.AccessTime = dir.ModTime
+	setOwnership(&dir, r.object, r.session)
+
+	dir.Qid.Type |= p9p.QTDIR
+	dir.Mode |= p9p.DMDIR
+	r.info = &dir
+
+	return dir
+}
+
+// newData returns a file containing object as formatted by marshal. The
+// object is marshalled when the file is read, with its apiVersion and kind,
+// which objects from the informer caches don't have.
+func newData(name string, object Object, session Session, marshal func(object runtime.Object) ([]byte, error)) *Dynamic {
+	return &Dynamic{
+		name:       name,
+		object:     object,
+		session:    session,
+		objectTime: true,
+		generate: func(ctx context.Context) ([]byte, error) {
+			return marshal(withKind(object))
+		},
+	}
+}
+
+// withKind returns a copy of object with its apiVersion and kind set from
+// the client scheme, if they are known.
+func withKind(object Object) runtime.Object {
+	copied := object.DeepCopyObject()
+	if kinds, _, err := scheme.Scheme.ObjectKinds(object); err == nil && len(kinds) > 0 {
+		copied.GetObjectKind().SetGroupVersionKind(kinds[0])
+	}
+
+	return copied
+}
+
+// Path returns where the object is found in its namespace or the cluster
+// directory.
+func (r *ObjectRef) Path() string {
+	return path.Join(resourcePath(r.resource, r.object.GetNamespace()), r.object.GetName())
+}
+
+// resourcePath returns the path of the directory of resource within
+// namespace, or within the cluster directory if namespace is empty.
+func resourcePath(resource schema.GroupVersionResource, namespace string) string {
+	if namespace == "" {
+		return path.Join("cluster", resource.Resource)
+	}
+
+	return path.Join("namespaces", namespace, resource.Resource)
+}
+
+func (r *ObjectRef) Get(name string) (Ref, error) {
+	if strings.HasPrefix(name, "events%") {
+		return selectEvents(name, r.object.GetNamespace(), r.session, involving(r.object))
+	}
+
+	ref, ok := r.children[name]
+	if !ok {
+		return nil, p9p.ErrNotfound
+	}
+
+	return ref, nil
+}
+
+func (r *ObjectRef) Read(ctx context.Context, p []byte, offset int64) (int, error) {
+	if r.readdir != nil {
+		return r.readdir.Read(ctx, p, offset)
+	}
+
+	dir := make([]p9p.Dir, 0, len(r.children))
+	for _, child := range r.children {
+		dir = append(dir, child.Info())
+	}
+
+	r.readdir = p9p.NewFixedReaddir(p9p.NewCodec(), dir)
+	return r.readdir.Read(ctx, p, offset)
+}
+
+// ownedBy reports if object has an owner reference to owner.
+func ownedBy(object metav1.Object, owner metav1.Object) bool {
+	for _, ref := range object.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setOwnership sets the owner of dir to the manager that first changed object,
+// and its last modifier to the manager that most recently changed it, as
+// recorded in the object's managed fields. Without an object or managed
+// fields, dir is owned by the session's user.
+func setOwnership(dir *p9p.Dir, object metav1.Object, session Session) {
+	uname, _ := session.GetAuth()
+	dir.UID = uname
+	dir.GID = uname
+	dir.MUID = "none"
+
+	if object == nil {
+		return
+	}
+
+	var first, last *metav1.ManagedFieldsEntry
+	for i := range object.GetManagedFields() {
+		entry := &object.GetManagedFields()[i]
+		if entry.Time == nil || entry.Manager == "" {
+			continue
+		}
+
+		if first == nil || entry.Time.Before(first.Time) {
+			first = entry
+		}
+		if last == nil || last.Time.Before(entry.Time) {
+			last = entry
+		}
+	}
+
+	if first != nil {
+		dir.UID = first.Manager
+		dir.GID = first.Manager
+		dir.MUID = last.Manager
+	}
+}
+
+// lastModified returns when object last changed: the newest of its creation,
+// managed fields and status condition transition times. Without an object, it
+// returns the current time.
+func lastModified(object metav1.Object) time.Time {
+	if object == nil {
+		return time.Now()
+	}
+
+	modified := object.GetCreationTimestamp().Time
+	for _, entry := range object.GetManagedFields() {
+		if entry.Time != nil && entry.Time.After(modified) {
+			modified = entry.Time.Time
+		}
+	}
+
+	for _, t := range transitionTimes(object) {
+		if t.After(modified) {
+			modified = t
+		}
+	}
+
+	return modified
+}
+
+// transitionTimes returns the last transition times of the status conditions
+// of object, read from the typed fields of the resources served.
+func transitionTimes(object metav1.Object) []time.Time {
+	var times []time.Time
+	switch object := object.(type) {
+	case *corev1.Pod:
+		for _, condition := range object.Status.Conditions {
+			times = append(times, condition.LastTransitionTime.Time)
+		}
+	case *corev1.Node:
+		for _, condition := range object.Status.Conditions {
+			times = append(times, condition.LastTransitionTime.Time)
+		}
+	case *corev1.Namespace:
+		for _, condition := range object.Status.Conditions {
+			times = append(times, condition.LastTransitionTime.Time)
+		}
+	case *appsv1.Deployment:
+		for _, condition := range object.Status.Conditions {
+			times = append(times, condition.LastTransitionTime.Time)
+		}
+	case *appsv1.StatefulSet:
+		for _, condition := range object.Status.Conditions {
+			times = append(times, condition.LastTransitionTime.Time)
+		}
+	case *appsv1.DaemonSet:
+		for _, condition := range object.Status.Conditions {
+			times = append(times, condition.LastTransitionTime.Time)
+		}
+	case *appsv1.ReplicaSet:
+		for _, condition := range object.Status.Conditions {
+			times = append(times, condition.LastTransitionTime.Time)
+		}
+	case *batchv1.Job:
+		for _, condition := range object.Status.Conditions {
+			times = append(times, condition.LastTransitionTime.Time)
+		}
+	case *unstructured.Unstructured:
+		conditions, _, _ := unstructured.NestedSlice(object.Object, "status", "conditions")
+		for _, condition := range conditions {
+			condition, ok := condition.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			transition, _, _ := unstructured.NestedString(condition, "lastTransitionTime")
+			if t, err := time.Parse(time.RFC3339, transition); err == nil {
+				times = append(times, t)
+			}
+		}
+	}
+
+	return times
+}
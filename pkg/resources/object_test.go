@@ -0,0 +1,112 @@
+package resources
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestLastModified(t *testing.T) {
+	created := time.Date(2019, 11, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) metav1.Time { return metav1.NewTime(created.Add(d)) }
+	meta := func(managed ...time.Duration) metav1.ObjectMeta {
+		m := metav1.ObjectMeta{Name: "web", CreationTimestamp: metav1.NewTime(created)}
+		for _, d := range managed {
+			t := at(d)
+			m.ManagedFields = append(m.ManagedFields, metav1.ManagedFieldsEntry{Manager: "kubectl", Time: &t})
+		}
+		return m
+	}
+
+	tests := []struct {
+		name   string
+		object metav1.Object
+		want   time.Time
+	}{
+		{
+			name:   "created",
+			object: &corev1.Pod{ObjectMeta: meta()},
+			want:   created,
+		},
+		{
+			name:   "managed fields",
+			object: &corev1.Pod{ObjectMeta: meta(time.Minute, 3*time.Minute, 2*time.Minute)},
+			want:   created.Add(3 * time.Minute),
+		},
+		{
+			name: "pod conditions",
+			object: &corev1.Pod{
+				ObjectMeta: meta(time.Minute),
+				Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, LastTransitionTime: at(5 * time.Minute)},
+					{Type: corev1.PodScheduled, LastTransitionTime: at(2 * time.Minute)},
+				}},
+			},
+			want: created.Add(5 * time.Minute),
+		},
+		{
+			name: "deployment conditions",
+			object: &appsv1.Deployment{
+				ObjectMeta: meta(time.Minute),
+				Status: appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{
+					{Type: appsv1.DeploymentAvailable, LastTransitionTime: at(4 * time.Minute)},
+				}},
+			},
+			want: created.Add(4 * time.Minute),
+		},
+		{
+			name: "unstructured conditions",
+			object: &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":              "web",
+					"creationTimestamp": "2019-11-01T12:00:00Z",
+				},
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Ready", "lastTransitionTime": "2019-11-01T12:06:00Z"},
+					},
+				},
+			}},
+			want: created.Add(6 * time.Minute),
+		},
+	}
+
+	for _, test := range tests {
+		if got := lastModified(test.object); !got.Equal(test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDataFiles(t *testing.T) {
+	session := newTestSession(t)
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	ref := NewObjectRef(deployment, deploymentsResource, session, nil)
+
+	for name, want := range map[string][]string{
+		"data.yaml": {"apiVersion: apps/v1\n", "kind: Deployment\n", "  name: web\n"},
+		"data.json": {`"apiVersion": "apps/v1",`, `"kind": "Deployment",`, `"name": "web",`},
+	} {
+		file, err := ref.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content := readAll(t, file, 0)
+		for _, want := range want {
+			if !strings.Contains(content, want) {
+				t.Errorf("%s doesn't contain %q:\n%s", name, want, content)
+			}
+		}
+	}
+
+	// The object from the cache is left unchanged.
+	if kind := deployment.GetObjectKind().GroupVersionKind(); !kind.Empty() {
+		t.Errorf("marshalling set the kind of the cached object to %v", kind)
+	}
+}
@@ -0,0 +1,112 @@
+package resources
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var podsResource = v1.SchemeGroupVersion.WithResource("pods")
+
+func NewPods(namespace string, session Session) *Collection {
+	lister := session.Informer().Core().V1().Pods().Lister().Pods(namespace)
+	return &Collection{
+		name:      "pods",
+		namespace: namespace,
+		resource:  podsResource,
+		session:   session,
+		list: func(selector labels.Selector) ([]Object, error) {
+			pods, err := lister.List(selector)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]Object, 0, len(pods))
+			for _, pod := range pods {
+				objects = append(objects, pod)
+			}
+			return objects, nil
+		},
+		get: func(name string) (Object, error) {
+			return lister.Get(name)
+		},
+		newRef: func(object Object) Ref {
+			return NewPodRef(object.(*v1.Pod), session)
+		},
+		columns: podColumns,
+	}
+}
+
+// podColumns are the _table columns of pods, following kubectl get pods.
+var podColumns = []column{
+	{"READY", func(object Object) string {
+		pod := object.(*v1.Pod)
+		ready := 0
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Ready {
+				ready++
+			}
+		}
+		return fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers))
+	}},
+	{"STATUS", func(object Object) string {
+		return podStatus(object.(*v1.Pod))
+	}},
+	{"RESTARTS", func(object Object) string {
+		restarts := int32(0)
+		for _, status := range object.(*v1.Pod).Status.ContainerStatuses {
+			restarts += status.RestartCount
+		}
+		return fmt.Sprint(restarts)
+	}},
+}
+
+// podStatus returns the status kubectl shows for pod: the reason a container
+// is waiting or terminated if there is one, otherwise the pod's phase.
+func podStatus(pod *v1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "Terminating"
+	}
+
+	status := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		status = pod.Status.Reason
+	}
+
+	for _, container := range pod.Status.ContainerStatuses {
+		if waiting := container.State.Waiting; waiting != nil && waiting.Reason != "" {
+			return waiting.Reason
+		}
+		if terminated := container.State.Terminated; terminated != nil && terminated.Reason != "" {
+			status = terminated.Reason
+		}
+	}
+
+	return status
+}
+
+// newOwnedPods returns a collection of the pods with an owner reference to
+// owner.
+func newOwnedPods(owner Object, session Session) *Collection {
+	return NewPods(owner.GetNamespace(), session).filter(func(object Object) bool {
+		return ownedBy(object, owner)
+	})
+}
+
+// NewPodRef returns the directory of a Pod. Once scheduled, the node
+// directory links to the node the pod is running on.
+func NewPodRef(pod *v1.Pod, session Session) *ObjectRef {
+	children := map[string]Ref{}
+	if pod.Spec.NodeName != "" {
+		children["node"] = &LinkRef{
+			name:    "node",
+			session: session,
+			resolve: func() (Ref, error) {
+				return NewNodes(session).Get(pod.Spec.NodeName)
+			},
+		}
+	}
+
+	return NewObjectRef(pod, podsResource, session, children)
+}
@@ -11,3 +11,69 @@ type Ref interface {
 	Get(name string) (Ref, error)
 	Read(ctx context.Context, p []byte, offset int64) (n int, err error)
 }
+
+// Writer is implemented by Refs that accept writes.
+type Writer interface {
+	Write(ctx context.Context, p []byte, offset int64) (n int, err error)
+}
+
+// Creator is implemented by directory Refs that allow creating files.
+type Creator interface {
+	Create(ctx context.Context, name string, perm uint32, mode p9p.Flag) (Ref, error)
+}
+
+// Pather is implemented by Refs that can be reached by several paths, such as
+// objects within label selections or owners directories. Path returns the
+// canonical path from the root of the cluster, without a leading slash, or
+// an empty string if there isn't one.
+type Pather interface {
+	Path() string
+}
+
+// Qider is implemented by Refs whose qid is much cheaper to find than the
+// rest of their Info, such as collections whose modification time depends on
+// every object. Walks only need the qid of each file.
+type Qider interface {
+	Qid() p9p.Qid
+}
+
+// Walker is implemented by Refs whose children may take a while to load, such
+// as the directories of clusters whose caches sync on first use. Walk is used
+// instead of Get, so the wait is bound to the request.
+type Walker interface {
+	Walk(ctx context.Context, name string) (Ref, error)
+}
+
+// Previewer is implemented by files that accept writes only to preview
+// changes, such as dryrun, without changing anything on the server. Writing
+// them isn't restricted by write policies.
+type Previewer interface {
+	Previews() bool
+}
+
+// Opener is implemented by Refs that keep state for each open fid, such as
+// files that buffer writes. The returned Ref is used for the opened fid.
+type Opener interface {
+	Open(ctx context.Context, mode p9p.Flag) (Ref, error)
+}
+
+// Closer is implemented by Refs returned by Opener that act when their fid is
+// clunked, such as committing buffered writes.
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// Remover is implemented by Refs that can be removed.
+type Remover interface {
+	Remove(ctx context.Context) error
+}
+
+// Modes of the files and directories served. Directories the user may change
+// the contents of have the write bits of modeWritable added.
+const (
+	modeReadOnly  = 0444
+	modeReadWrite = 0660
+	modeWriteOnly = 0220
+	modeDir       = 0555
+	modeWritable  = 0220
+)
@@ -0,0 +1,245 @@
+package resources
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/docker/go-p9p"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// collections maps the Kind of each supported resource type to the
+// constructor of its collection. Cluster-scoped constructors ignore the
+// namespace.
+func collections() map[string]func(namespace string, session Session) *Collection {
+	return map[string]func(namespace string, session Session) *Collection{
+		"Deployment":  NewDeployments,
+		"StatefulSet": NewStatefulSets,
+		"DaemonSet":   NewDaemonSets,
+		"ReplicaSet":  NewReplicaSets,
+		"Job":         NewJobs,
+		"CronJob":     NewCronJobs,
+		"Pod":         NewPods,
+		"Node": func(namespace string, session Session) *Collection {
+			return NewNodes(session)
+		},
+	}
+}
+
+// LinkRef mirrors the directory of another object under a different name.
+// 9P2000 has no symbolic links, so relationships between objects are exposed
+// as copies of the target directory.
+type LinkRef struct {
+	name    string
+	session Session
+	resolve func() (Ref, error)
+	target  Ref
+	info    *p9p.Dir
+}
+
+func (r *LinkRef) get() (Ref, error) {
+	if r.target != nil {
+		return r.target, nil
+	}
+
+	target, err := r.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	r.target = target
+	return target, nil
+}
+
+func (r *LinkRef) Info() p9p.Dir {
+	if r.info != nil {
+		return *r.info
+	}
+
+	target, err := r.get()
+	if err != nil {
+		dir := p9p.Dir{}
+		dir.Qid.Path = rand.Uint64()
+		dir.Name = r.name
+		dir.Mode = modeDir
+		dir.AccessTime = time.Now()
+		dir.ModTime = time.Now()
+		setOwnership(&dir, nil, r.session)
+
+		dir.Qid.Type |= p9p.QTDIR
+		dir.Mode |= p9p.DMDIR
+		return dir
+	}
+
+	dir := target.Info()
+	dir.Name = r.name
+	r.info = &dir
+
+	return dir
+}
+
+// Path returns the path of the target.
+func (r *LinkRef) Path() string {
+	target, err := r.get()
+	if err != nil {
+		return ""
+	}
+	if pather, ok := target.(Pather); ok {
+		return pather.Path()
+	}
+
+	return ""
+}
+
+func (r *LinkRef) Get(name string) (Ref, error) {
+	target, err := r.get()
+	if err != nil {
+		return nil, err
+	}
+
+	return target.Get(name)
+}
+
+func (r *LinkRef) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	target, err := r.get()
+	if err != nil {
+		return 0, err
+	}
+
+	return target.Read(ctx, p, offset)
+}
+
+// RelationsRef is a directory of objects related to an object, such as its
+// owners. Related objects are grouped into collection directories, so
+// owners/deployments/web mirrors namespaces/default/deployments/web. The
+// relations are found when the directory is read or walked.
+type RelationsRef struct {
+	name        string
+	session     Session
+	object      Object
+	collections func() ([]*Collection, error)
+	info        *p9p.Dir
+	readdir     *p9p.Readdir
+}
+
+// newOwners returns a directory of the objects owner references of object
+// point to.
+func newOwners(object Object, session Session) *RelationsRef {
+	return &RelationsRef{
+		name:    "owners",
+		object:  object,
+		session: session,
+		collections: func() ([]*Collection, error) {
+			uids := make(map[string]map[types.UID]bool)
+			for _, ref := range object.GetOwnerReferences() {
+				if uids[ref.Kind] == nil {
+					uids[ref.Kind] = make(map[types.UID]bool)
+				}
+				uids[ref.Kind][ref.UID] = true
+			}
+
+			var owners []*Collection
+			for kind, newCollection := range collections() {
+				kind := kind
+				if uids[kind] == nil {
+					continue
+				}
+
+				owners = append(owners, newCollection(object.GetNamespace(), session).filter(func(owner Object) bool {
+					return uids[kind][owner.GetUID()]
+				}))
+			}
+
+			return owners, nil
+		},
+	}
+}
+
+// newOwned returns a directory of the objects with an owner reference to
+// object.
+func newOwned(object Object, session Session) *RelationsRef {
+	return &RelationsRef{
+		name:    "owned",
+		object:  object,
+		session: session,
+		collections: func() ([]*Collection, error) {
+			var owned []*Collection
+			for _, newCollection := range collections() {
+				collection := newCollection(object.GetNamespace(), session).filter(func(dependent Object) bool {
+					return ownedBy(dependent, object)
+				})
+
+				dependents, err := collection.list(labels.Everything())
+				if err != nil {
+					return nil, err
+				}
+				if len(dependents) == 0 {
+					continue
+				}
+
+				owned = append(owned, collection)
+			}
+
+			return owned, nil
+		},
+	}
+}
+
+func (r *RelationsRef) Info() p9p.Dir {
+	if r.info != nil {
+		return *r.info
+	}
+
+	dir := p9p.Dir{}
+	dir.Qid.Path = rand.Uint64()
+	dir.Qid.Version = 0
+
+	dir.Name = r.name
+	dir.Mode = modeDir
+	dir.Length = 0
+	dir.ModTime = lastModified(r.object)
+	dir.AccessTime = dir.ModTime
+	setOwnership(&dir, r.object, r.session)
+
+	dir.Qid.Type |= p9p.QTDIR
+	dir.Mode |= p9p.DMDIR
+	r.info = &dir
+
+	return dir
+}
+
+func (r *RelationsRef) Get(name string) (Ref, error) {
+	collections, err := r.collections()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, collection := range collections {
+		if collection.name == name {
+			return collection, nil
+		}
+	}
+
+	return nil, p9p.ErrNotfound
+}
+
+func (r *RelationsRef) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	if r.readdir != nil {
+		return r.readdir.Read(ctx, p, offset)
+	}
+
+	collections, err := r.collections()
+	if err != nil {
+		return 0, err
+	}
+
+	dir := make([]p9p.Dir, 0, len(collections))
+	for _, collection := range collections {
+		dir = append(dir, collection.Info())
+	}
+
+	r.readdir = p9p.NewFixedReaddir(p9p.NewCodec(), dir)
+	return r.readdir.Read(ctx, p, offset)
+}
@@ -0,0 +1,82 @@
+package resources
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var replicaSetsResource = v1.SchemeGroupVersion.WithResource("replicasets")
+
+func NewReplicaSets(namespace string, session Session) *Collection {
+	lister := session.Informer().Apps().V1().ReplicaSets().Lister().ReplicaSets(namespace)
+	return &Collection{
+		name:      "replicasets",
+		namespace: namespace,
+		resource:  replicaSetsResource,
+		session:   session,
+		list: func(selector labels.Selector) ([]Object, error) {
+			replicaSets, err := lister.List(selector)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]Object, 0, len(replicaSets))
+			for _, replicaSet := range replicaSets {
+				objects = append(objects, replicaSet)
+			}
+			return objects, nil
+		},
+		get: func(name string) (Object, error) {
+			return lister.Get(name)
+		},
+		newRef: func(object Object) Ref {
+			return NewReplicaSetRef(object.(*v1.ReplicaSet), session)
+		},
+		columns: replicaSetColumns,
+	}
+}
+
+// replicaSetColumns are the _table columns of replicasets, following kubectl
+// get replicasets.
+var replicaSetColumns = []column{
+	{"DESIRED", func(object Object) string {
+		return fmt.Sprint(*object.(*v1.ReplicaSet).Spec.Replicas)
+	}},
+	{"CURRENT", func(object Object) string {
+		return fmt.Sprint(object.(*v1.ReplicaSet).Status.Replicas)
+	}},
+	{"READY", func(object Object) string {
+		return fmt.Sprint(object.(*v1.ReplicaSet).Status.ReadyReplicas)
+	}},
+}
+
+func NewReplicaSetRef(replicaSet *v1.ReplicaSet, session Session) *ObjectRef {
+	return NewObjectRef(replicaSet, replicaSetsResource, session, map[string]Ref{
+		"scale": newScaleCtl(replicaSet, replicaSetsResource, *replicaSet.Spec.Replicas, session),
+		"rollout": &Static{
+			name:    "rollout",
+			content: []byte(replicaSetRolloutStatus(replicaSet)),
+			object:  replicaSet,
+			session: session,
+		},
+		"pods": newOwnedPods(replicaSet, session),
+	})
+}
+
+// replicaSetRolloutStatus describes how many of the desired replicas are
+// available. ReplicaSets have no rollouts of their own, but this mirrors the
+// rollout file of the other workloads.
+func replicaSetRolloutStatus(replicaSet *v1.ReplicaSet) string {
+	if replicaSet.Generation > replicaSet.Status.ObservedGeneration {
+		return "Waiting for replica set spec update to be observed...\n"
+	}
+
+	status := replicaSet.Status
+	if status.AvailableReplicas < *replicaSet.Spec.Replicas {
+		return fmt.Sprintf("Waiting for replica set %q: %d of %d replicas are available...\n", replicaSet.Name, status.AvailableReplicas, *replicaSet.Spec.Replicas)
+	}
+
+	return fmt.Sprintf("replica set %q has %d of %d replicas available\n", replicaSet.Name, status.AvailableReplicas, *replicaSet.Spec.Replicas)
+}
@@ -1,12 +1,133 @@
 package resources
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path"
+
 	"github.com/docker/go-p9p"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 type Session interface {
 	p9p.Session
 	GetAuth() (uname, aname string)
+	Client() kubernetes.Interface
+	Dynamic() dynamic.Interface
 	Informer() informers.SharedInformerFactory
+	Access() *Access
+}
+
+// apiPath returns the path of the named object of resource in namespace, and
+// of its subresource if given. If name is empty, it returns the path of every
+// object of resource.
+func apiPath(resource schema.GroupVersionResource, namespace string, name string, subresource ...string) []string {
+	prefix := path.Join("/apis", resource.Group, resource.Version)
+	if resource.Group == "" {
+		prefix = path.Join("/api", resource.Version)
+	}
+	segments := []string{prefix}
+	if namespace != "" {
+		segments = append(segments, "namespaces", namespace)
+	}
+	segments = append(segments, resource.Resource)
+	if name != "" {
+		segments = append(segments, name)
+	}
+
+	return append(segments, subresource...)
+}
+
+// newRequest returns a request to segments of the API, which is cancelled
+// when ctx is done. The typed and dynamic clients don't take a context, so a
+// flushed request would otherwise continue on the server.
+func newRequest(ctx context.Context, session Session, verb string, segments []string) (*rest.Request, error) {
+	client := session.Client().Discovery().RESTClient()
+	if client == nil {
+		return nil, errors.New("no REST client")
+	}
+
+	return client.Verb(verb).AbsPath(segments...).Context(ctx), nil
+}
+
+// getObject gets the named object of resource.
+func getObject(ctx context.Context, session Session, resource schema.GroupVersionResource, namespace string, name string) (*unstructured.Unstructured, error) {
+	req, err := newRequest(ctx, session, "GET", apiPath(resource, namespace, name))
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := req.DoRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	object := &unstructured.Unstructured{}
+	if err := object.UnmarshalJSON(body); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// updateObject replaces object, an object of resource, returning the result.
+// With dryRun, the update is only validated and nothing is changed.
+func updateObject(ctx context.Context, session Session, resource schema.GroupVersionResource, object *unstructured.Unstructured, dryRun bool) (*unstructured.Unstructured, error) {
+	data, err := object.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := newRequest(ctx, session, "PUT", apiPath(resource, object.GetNamespace(), object.GetName()))
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		req = req.Param("dryRun", metav1.DryRunAll)
+	}
+
+	body, err := req.SetHeader("Content-Type", "application/json").Body(data).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &unstructured.Unstructured{}
+	if err := result.UnmarshalJSON(body); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// createObject creates object, an object of resource in namespace.
+func createObject(ctx context.Context, session Session, resource schema.GroupVersionResource, namespace string, object interface{}) error {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+
+	req, err := newRequest(ctx, session, "POST", apiPath(resource, namespace, ""))
+	if err != nil {
+		return err
+	}
+
+	_, err = req.SetHeader("Content-Type", "application/json").Body(data).DoRaw()
+	return err
+}
+
+// patchObject applies a JSON merge patch to the named object of resource.
+func patchObject(ctx context.Context, session Session, resource schema.GroupVersionResource, namespace string, name string, patch []byte) error {
+	req, err := newRequest(ctx, session, "PATCH", apiPath(resource, namespace, name))
+	if err != nil {
+		return err
+	}
+
+	_, err = req.SetHeader("Content-Type", string(types.MergePatchType)).Body(patch).DoRaw()
+	return err
 }
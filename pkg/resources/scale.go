@@ -0,0 +1,55 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// newScaleCtl returns a scale file containing the replica count. Writing a
+// number to the file updates the scale subresource of object, an object of
+// resource.
+func newScaleCtl(object Object, resource schema.GroupVersionResource, replicas int32, session Session) *Ctl {
+	return &Ctl{
+		name:    "scale",
+		content: []byte(strconv.Itoa(int(replicas)) + "\n"),
+		object:  object,
+		session: session,
+		write: func(ctx context.Context, p []byte) error {
+			replicas, err := strconv.ParseInt(string(p), 10, 32)
+			if err != nil {
+				return err
+			}
+
+			segments := apiPath(resource, object.GetNamespace(), object.GetName(), "scale")
+			req, err := newRequest(ctx, session, "GET", segments)
+			if err != nil {
+				return err
+			}
+			body, err := req.DoRaw()
+			if err != nil {
+				return err
+			}
+
+			var scale autoscalingv1.Scale
+			if err := json.Unmarshal(body, &scale); err != nil {
+				return err
+			}
+			scale.Spec.Replicas = int32(replicas)
+
+			data, err := json.Marshal(&scale)
+			if err != nil {
+				return err
+			}
+			req, err = newRequest(ctx, session, "PUT", segments)
+			if err != nil {
+				return err
+			}
+			_, err = req.SetHeader("Content-Type", "application/json").Body(data).DoRaw()
+			return err
+		},
+	}
+}
@@ -0,0 +1,40 @@
+package resources
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScaleCtl(t *testing.T) {
+	session := newTestSession(t)
+	requests, closeServer := serveTestAPI(t, session, func(request apiRequest) string {
+		if request.method == "PUT" {
+			return request.body
+		}
+		return `{"apiVersion":"autoscaling/v1","kind":"Scale","metadata":{"name":"web","namespace":"default","resourceVersion":"7"},"spec":{"replicas":1}}`
+	})
+	defer closeServer()
+
+	deployment := testDeployment()
+	ctl := newScaleCtl(deployment, deploymentsResource, 1, session)
+	if err := ctl.write(context.Background(), []byte("3")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("got %d requests, want 2", len(got))
+	}
+	for i, method := range []string{"GET", "PUT"} {
+		if got[i].method != method || got[i].path != "/apis/apps/v1/namespaces/default/deployments/web/scale" {
+			t.Errorf("got %s %s, want %s of the scale subresource", got[i].method, got[i].path, method)
+		}
+	}
+
+	// The scale is updated with the version read, so a concurrent change
+	// isn't overwritten.
+	want := `{"kind":"Scale","apiVersion":"autoscaling/v1","metadata":{"name":"web","namespace":"default","resourceVersion":"7","creationTimestamp":null},"spec":{"replicas":3},"status":{"replicas":0}}`
+	if got[1].body != want {
+		t.Errorf("got update %s, want %s", got[1].body, want)
+	}
+}
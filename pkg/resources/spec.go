@@ -0,0 +1,167 @@
+package resources
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/docker/go-p9p"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// lastAppliedAnnotation is the annotation kubectl apply records the applied
+// configuration in.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Spec is the spec.yaml file of an object: the object without its status and
+// the metadata the server manages, suitable for copying to another namespace
+// or cluster.
+//
+// Each open fid reads the object as it was when opened, and edits are
+// buffered until the fid is clunked. The edited manifest then updates the
+// object, failing if it changed since the fid was opened.
+type Spec struct {
+	object   Object
+	resource schema.GroupVersionResource
+	session  Session
+	info     *p9p.Dir
+
+	live    *unstructured.Unstructured
+	content []byte
+	dirty   bool
+}
+
+func newSpec(object Object, resource schema.GroupVersionResource, session Session) *Spec {
+	return &Spec{
+		object:   object,
+		resource: resource,
+		session:  session,
+	}
+}
+
+func (r *Spec) Info() p9p.Dir {
+	if r.info != nil {
+		return *r.info
+	}
+
+	dir := p9p.Dir{}
+	dir.Qid.Path = rand.Uint64()
+	dir.Qid.Version = 0
+
+	dir.Name = "spec.yaml"
+	dir.Mode = modeReadWrite
+	dir.Length = uint64(len(r.content))
+	dir.ModTime = lastModified(r.object)
+	dir.AccessTime = dir.ModTime
+	setOwnership(&dir, r.object, r.session)
+
+	dir.Qid.Type |= p9p.QTFILE
+
+	r.info = &dir
+	return dir
+}
+
+func (r *Spec) Get(name string) (Ref, error) {
+	return nil, p9p.ErrWalknodir
+}
+
+// Open returns a Spec for the opened fid, containing the live object.
+func (r *Spec) Open(ctx context.Context, mode p9p.Flag) (Ref, error) {
+	live, err := getObject(ctx, r.session, r.resource, r.object.GetNamespace(), r.object.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	opened := newSpec(r.object, r.resource, r.session)
+	opened.live = live
+
+	if mode&p9p.OTRUNC == 0 {
+		opened.content, err = yaml.Marshal(cleanObject(live).Object)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return opened, nil
+}
+
+func (r *Spec) Read(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	return readAt(p, r.content, offset)
+}
+
+func (r *Spec) Write(ctx context.Context, p []byte, offset int64) (n int, err error) {
+	// Writing from the start replaces the manifest, as editors rewrite the
+	// whole file when saving.
+	if !r.dirty && offset == 0 {
+		r.content = r.content[:0]
+	}
+
+	// Writes may overwrite the manifest or extend it, but not leave holes.
+	if offset < 0 || offset > int64(len(r.content)) {
+		return 0, p9p.ErrBadoffset
+	}
+
+	if end := offset + int64(len(p)); end > int64(len(r.content)) {
+		r.content = append(r.content, make([]byte, end-int64(len(r.content)))...)
+	}
+
+	r.dirty = true
+	return copy(r.content[offset:], p), nil
+}
+
+// Close updates the object with the edited manifest, if it was written.
+func (r *Spec) Close(ctx context.Context) error {
+	if !r.dirty {
+		return nil
+	}
+
+	manifest, err := parseManifest(r.content, r.live)
+	if err != nil {
+		return err
+	}
+
+	// Keep the last applied configuration hidden from the clean view, so
+	// kubectl apply continues to work.
+	if applied, ok := r.live.GetAnnotations()[lastAppliedAnnotation]; ok {
+		annotations := manifest.GetAnnotations()
+		if _, ok := annotations[lastAppliedAnnotation]; !ok {
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[lastAppliedAnnotation] = applied
+			manifest.SetAnnotations(annotations)
+		}
+	}
+
+	_, err = updateObject(ctx, r.session, r.resource, manifest, false)
+	if err != nil {
+		// Clients often ignore errors when closing files, so make sure
+		// failed updates are noticed.
+		log.Warn().Err(err).Str("name", r.object.GetName()).Msg("error updating from spec.yaml")
+	}
+	return err
+}
+
+// cleanObject returns a copy of object without its status, managed fields,
+// namespace, owner references and the metadata set by the server, which
+// parseManifest fills in from the live object when writing back.
+func cleanObject(object *unstructured.Unstructured) *unstructured.Unstructured {
+	clean := object.DeepCopy()
+
+	unstructured.RemoveNestedField(clean.Object, "status")
+	for _, field := range []string{"managedFields", "uid", "resourceVersion", "creationTimestamp", "generation", "selfLink", "namespace", "ownerReferences"} {
+		unstructured.RemoveNestedField(clean.Object, "metadata", field)
+	}
+
+	annotations := clean.GetAnnotations()
+	delete(annotations, lastAppliedAnnotation)
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(clean.Object, "metadata", "annotations")
+	} else {
+		clean.SetAnnotations(annotations)
+	}
+
+	return clean
+}
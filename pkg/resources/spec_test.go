@@ -0,0 +1,151 @@
+package resources
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/go-p9p"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+func TestCleanObject(t *testing.T) {
+	live := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "ReplicaSet",
+		"metadata": map[string]interface{}{
+			"name":              "web-123",
+			"namespace":         "default",
+			"uid":               "web-123-uid",
+			"resourceVersion":   "7",
+			"generation":        int64(2),
+			"creationTimestamp": "2019-11-01T12:00:00Z",
+			"selfLink":          "/apis/apps/v1/namespaces/default/replicasets/web-123",
+			"labels":            map[string]interface{}{"app": "web"},
+			"annotations": map[string]interface{}{
+				lastAppliedAnnotation: "{}",
+			},
+			"ownerReferences": []interface{}{
+				map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "web", "uid": "web-uid"},
+			},
+			"managedFields": []interface{}{
+				map[string]interface{}{"manager": "kube-controller-manager", "operation": "Update"},
+			},
+		},
+		"spec":   map[string]interface{}{"replicas": int64(1)},
+		"status": map[string]interface{}{"replicas": int64(1)},
+	}}
+
+	clean, err := yaml.Marshal(cleanObject(live).Object)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  labels:
+    app: web
+  name: web-123
+spec:
+  replicas: 1
+`
+	if string(clean) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", clean, want)
+	}
+
+	// Writing the clean view back keeps what it left out.
+	manifest, err := parseManifest(clean, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := manifest.GetNamespace(); got != "default" {
+		t.Errorf("got namespace %q, want default", got)
+	}
+	if got := manifest.GetOwnerReferences(); len(got) != 1 || got[0].UID != "web-uid" {
+		t.Errorf("got owner references %v, want those of the live object", got)
+	}
+
+	// Giving an empty list removes the owner references.
+	manifest, err = parseManifest([]byte(strings.Replace(string(clean), "metadata:\n", "metadata:\n  ownerReferences: []\n", 1)), live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := manifest.GetOwnerReferences(); len(got) != 0 {
+		t.Errorf("got owner references %v, want none", got)
+	}
+}
+
+func TestSpecCloseCancel(t *testing.T) {
+	session := newTestSession(t)
+
+	// The update hangs until the client goes away.
+	updating := make(chan struct{})
+	cancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(testDeploymentJSON))
+			return
+		}
+
+		// Reading the request lets the server notice the client going away.
+		ioutil.ReadAll(r.Body)
+		close(updating)
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	defer server.Close()
+
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.client = client
+
+	ref, err := newSpec(testDeployment(), deploymentsResource, session).Open(context.Background(), p9p.ORDWR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := ref.(*Spec)
+	if _, err := spec.Write(context.Background(), []byte("spec:\n  replicas: 3\n"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- spec.Close(ctx)
+	}()
+
+	select {
+	case <-updating:
+	case <-time.After(5 * time.Second):
+		t.Fatal("update was not requested")
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("closing succeeded after cancelling the update")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("closing did not return after cancelling")
+	}
+
+	// The update is abandoned by the server too, rather than landing after
+	// the clunk reported failure.
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("update request was not cancelled")
+	}
+}
@@ -0,0 +1,91 @@
+package resources
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var statefulSetsResource = v1.SchemeGroupVersion.WithResource("statefulsets")
+
+func NewStatefulSets(namespace string, session Session) *Collection {
+	lister := session.Informer().Apps().V1().StatefulSets().Lister().StatefulSets(namespace)
+	return &Collection{
+		name:      "statefulsets",
+		namespace: namespace,
+		resource:  statefulSetsResource,
+		session:   session,
+		list: func(selector labels.Selector) ([]Object, error) {
+			statefulSets, err := lister.List(selector)
+			if err != nil {
+				return nil, err
+			}
+
+			objects := make([]Object, 0, len(statefulSets))
+			for _, statefulSet := range statefulSets {
+				objects = append(objects, statefulSet)
+			}
+			return objects, nil
+		},
+		get: func(name string) (Object, error) {
+			return lister.Get(name)
+		},
+		newRef: func(object Object) Ref {
+			return NewStatefulSetRef(object.(*v1.StatefulSet), session)
+		},
+		columns: statefulSetColumns,
+	}
+}
+
+// statefulSetColumns are the _table columns of statefulsets, following
+// kubectl get statefulsets.
+var statefulSetColumns = []column{
+	{"READY", func(object Object) string {
+		statefulSet := object.(*v1.StatefulSet)
+		return fmt.Sprintf("%d/%d", statefulSet.Status.ReadyReplicas, *statefulSet.Spec.Replicas)
+	}},
+}
+
+func NewStatefulSetRef(statefulSet *v1.StatefulSet, session Session) *ObjectRef {
+	return NewObjectRef(statefulSet, statefulSetsResource, session, map[string]Ref{
+		"scale": newScaleCtl(statefulSet, statefulSetsResource, *statefulSet.Spec.Replicas, session),
+		"rollout": &Static{
+			name:    "rollout",
+			content: []byte(statefulSetRolloutStatus(statefulSet)),
+			object:  statefulSet,
+			session: session,
+		},
+		"pods": newOwnedPods(statefulSet, session),
+	})
+}
+
+// statefulSetRolloutStatus describes the progress of a rollout, following the
+// messages of kubectl rollout status.
+func statefulSetRolloutStatus(statefulSet *v1.StatefulSet) string {
+	if statefulSet.Spec.UpdateStrategy.Type != v1.RollingUpdateStatefulSetStrategyType {
+		return fmt.Sprintf("rollout status is only available for %s strategy type\n", v1.RollingUpdateStatefulSetStrategyType)
+	}
+	if statefulSet.Generation > statefulSet.Status.ObservedGeneration {
+		return "Waiting for statefulset spec update to be observed...\n"
+	}
+
+	status := statefulSet.Status
+	if status.ReadyReplicas < *statefulSet.Spec.Replicas {
+		return fmt.Sprintf("Waiting for %d pods to be ready...\n", *statefulSet.Spec.Replicas-status.ReadyReplicas)
+	}
+
+	if rollingUpdate := statefulSet.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil && rollingUpdate.Partition != nil {
+		partitioned := *statefulSet.Spec.Replicas - *rollingUpdate.Partition
+		if status.UpdatedReplicas < partitioned {
+			return fmt.Sprintf("Waiting for partitioned roll out to finish: %d out of %d new pods have been updated...\n", status.UpdatedReplicas, partitioned)
+		}
+		return fmt.Sprintf("partitioned roll out complete: %d new pods have been updated...\n", status.UpdatedReplicas)
+	}
+
+	if status.UpdateRevision != status.CurrentRevision {
+		return fmt.Sprintf("waiting for statefulset rolling update to complete %d pods at revision %s...\n", status.UpdatedReplicas, status.UpdateRevision)
+	}
+
+	return fmt.Sprintf("statefulset rolling update complete %d pods at revision %s...\n", status.CurrentReplicas, status.CurrentRevision)
+}
@@ -3,17 +3,21 @@ package resources
 import (
 	"context"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/docker/go-p9p"
 )
 
 type DirRef struct {
-	path     string
-	info     p9p.Dir
-	session  Session
+	path    string
+	info    p9p.Dir
+	session Session
+	load    func(ctx context.Context) (map[string]Ref, error)
+	readdir *p9p.Readdir
+
+	mu       sync.Mutex
 	children map[string]Ref
-	readdir  *p9p.Readdir
 }
 
 func NewDirRef(path string, session Session, children map[string]Ref) *DirRef {
@@ -27,21 +31,55 @@ func NewDirRef(path string, session Session, children map[string]Ref) *DirRef {
 	return d
 }
 
+// NewLazyDirRef returns a directory whose children are created by load when
+// the directory is first walked or read. Loading is bound to the context of
+// the request, and retried by later requests if it fails.
+func NewLazyDirRef(path string, session Session, load func(ctx context.Context) (map[string]Ref, error)) *DirRef {
+	d := &DirRef{
+		path:    path,
+		session: session,
+		load:    load,
+	}
+	d.info = d.createInfo()
+
+	return d
+}
+
+// loadChildren returns the children of the directory, loading them if
+// needed. The lock isn't held while loading, so a slow load doesn't block
+// requests that are given up on.
+func (d *DirRef) loadChildren(ctx context.Context) (map[string]Ref, error) {
+	d.mu.Lock()
+	children := d.children
+	d.mu.Unlock()
+	if children != nil || d.load == nil {
+		return children, nil
+	}
+
+	children, err := d.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.children == nil {
+		d.children = children
+	}
+	return d.children, nil
+}
+
 func (d *DirRef) createInfo() p9p.Dir {
 	dir := p9p.Dir{}
 	dir.Qid.Path = rand.Uint64()
 	dir.Qid.Version = 0
 
 	dir.Name = d.path
-	dir.Mode = 0664
+	dir.Mode = modeDir
 	dir.Length = 0
 	dir.AccessTime = time.Now()
 	dir.ModTime = time.Now()
-	dir.MUID = "none"
-
-	uname, _ := d.session.GetAuth()
-	dir.UID = uname
-	dir.GID = uname
+	setOwnership(&dir, nil, d.session)
 
 	dir.Qid.Type |= p9p.QTDIR
 	dir.Mode |= p9p.DMDIR
@@ -54,7 +92,18 @@ func (d *DirRef) Info() p9p.Dir {
 }
 
 func (d *DirRef) Get(name string) (Ref, error) {
-	child, ok := d.children[name]
+	return d.Walk(context.Background(), name)
+}
+
+// Walk returns the named child, loading the children of a lazy directory
+// with ctx.
+func (d *DirRef) Walk(ctx context.Context, name string) (Ref, error) {
+	children, err := d.loadChildren(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	child, ok := children[name]
 	if !ok {
 		return nil, p9p.ErrNotfound
 	}
@@ -67,8 +116,13 @@ func (d *DirRef) Read(ctx context.Context, p []byte, offset int64) (n int, err e
 		return d.readdir.Read(ctx, p, offset)
 	}
 
-	dir := make([]p9p.Dir, 0, len(d.children))
-	for _, child := range d.children {
+	children, err := d.loadChildren(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	dir := make([]p9p.Dir, 0, len(children))
+	for _, child := range children {
 		dir = append(dir, child.Info())
 	}
 	d.readdir = p9p.NewFixedReaddir(p9p.NewCodec(), dir)
@@ -0,0 +1,90 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// tableAccept requests the server-side Table rendering of objects, falling
+// back to the v1beta1 Table of older API servers.
+const tableAccept = "application/json;as=Table;v=v1;g=meta.k8s.io,application/json;as=Table;v=v1beta1;g=meta.k8s.io"
+
+// newTable returns a table file containing the columns kubectl get -o wide
+// shows for object, as printed by the API server. The file has a header line
+// and a line for the object, with tab separated columns.
+func newTable(object Object, resource schema.GroupVersionResource, session Session) *Dynamic {
+	return &Dynamic{
+		name:    "table",
+		object:  object,
+		session: session,
+		generate: func(ctx context.Context) ([]byte, error) {
+			table, err := getTable(ctx, session, resource, object.GetNamespace(), object.GetName())
+			if err != nil {
+				return nil, err
+			}
+
+			return formatTable(table), nil
+		},
+	}
+}
+
+// getTable gets the named object, or all objects if name is empty, as a
+// server-side Table.
+func getTable(ctx context.Context, session Session, resource schema.GroupVersionResource, namespace string, name string) (*metav1.Table, error) {
+	req, err := newRequest(ctx, session, "GET", apiPath(resource, namespace, name))
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := req.
+		SetHeader("Accept", tableAccept).
+		Param("includeObject", "None").
+		DoRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode numbers as written, so large integers aren't printed in
+	// exponent form.
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var table metav1.Table
+	if err := decoder.Decode(&table); err != nil {
+		return nil, err
+	}
+
+	return &table, nil
+}
+
+// formatTable formats every column of a Table with tab separated columns,
+// after a header line of the column names.
+func formatTable(table *metav1.Table) []byte {
+	var buf bytes.Buffer
+
+	names := make([]string, 0, len(table.ColumnDefinitions))
+	for _, column := range table.ColumnDefinitions {
+		names = append(names, strings.ToUpper(column.Name))
+	}
+	fmt.Fprintln(&buf, strings.Join(names, "\t"))
+
+	for _, row := range table.Rows {
+		cells := make([]string, 0, len(row.Cells))
+		for _, cell := range row.Cells {
+			if cell == nil {
+				cells = append(cells, "<none>")
+				continue
+			}
+			cells = append(cells, fmt.Sprint(cell))
+		}
+		fmt.Fprintln(&buf, strings.Join(cells, "\t"))
+	}
+
+	return buf.Bytes()
+}